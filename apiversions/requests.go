@@ -27,6 +27,10 @@ func Get(client *gophercloud.ServiceClient) GetResult {
 	reqOpts := gophercloud.RequestOpts{
 		OkCodes: []int{http.StatusOK, http.StatusMultipleChoices},
 	}
-	_, res.Err = client.Get(getURL(client), &res.Body, &reqOpts)
+	resp, err := client.Get(getURL(client), &res.Body, &reqOpts)
+	if resp != nil {
+		res.StatusCode = resp.StatusCode
+	}
+	res.Err = err
 	return res
 }
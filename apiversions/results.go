@@ -30,10 +30,15 @@ type APIVersion struct {
 // GetResult represents the result of a get operation.
 type GetResult struct {
 	gophercloud.Result
+	// StatusCode is HTTP status code of response, zero if no response was received
+	StatusCode int
 }
 
 // Extract will get the Volume object out of the commonResult object.
 func (r GetResult) Extract() ([]APIVersion, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
 
 	var resp struct {
 		Versions []APIVersion `mapstructure:"versions"`
@@ -0,0 +1,83 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/services"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// listing represents images retrieved from Glance together with time of retrieval
+type listing struct {
+	images   []types.Image
+	listedAt time.Time
+}
+
+// merge returns listing with images of both listings, which is as old as the older of them
+func (l listing) merge(other listing) listing {
+	merged := listing{images: append(append([]types.Image{}, l.images...), other.images...), listedAt: l.listedAt}
+	if merged.listedAt.IsZero() || (!other.listedAt.IsZero() && other.listedAt.Before(merged.listedAt)) {
+		merged.listedAt = other.listedAt
+	}
+	return merged
+}
+
+// listImages returns images of given target from cache if cached listing is not older than cache TTL,
+// otherwise images are listed by Glance and listing is cached
+func (c *collector) listImages(service services.Service, provider *gophercloud.ProviderClient, t target) (listing, error) {
+	// listing is cached by credentials and Glance endpoint, so tasks collecting the same tenant share it
+	key := endpointKey{auth: t.auth, endpoint: t.endpoint}
+
+	c.mutex.Lock()
+	cached, found := c.listings[key]
+	c.mutex.Unlock()
+
+	if found && t.cacheTTL > 0 && time.Since(cached.listedAt) <= t.cacheTTL {
+		c.countCacheHit()
+		return cached, nil
+	}
+
+	started := time.Now()
+	imgs, err := service.ListImages(provider)
+	c.countListing(started, err)
+	if err != nil {
+		return listing{}, err
+	}
+
+	list := listing{images: imgs, listedAt: time.Now()}
+	if t.cacheTTL > 0 {
+		c.mutex.Lock()
+		c.listings[key] = list
+		c.mutex.Unlock()
+	}
+
+	return list, nil
+}
+
+// withDataAge returns copy of metric tagged with age of image listing it was calculated from, in seconds
+func withDataAge(metric plugin.MetricType, list listing) plugin.MetricType {
+	if list.listedAt.IsZero() {
+		return metric
+	}
+	age := time.Since(list.listedAt).Seconds()
+	return withTag(metric, "data_age", strconv.FormatFloat(age, 'f', 0, 64))
+}
@@ -0,0 +1,36 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/intelsdi-x/snap/control/plugin"
+)
+
+// withCloud returns copy of metric tagged with name of the cloud it was collected from
+func withCloud(metric plugin.MetricType, cloud string) plugin.MetricType {
+	return withTag(metric, "cloud", cloud)
+}
+
+// withTag returns copy of metric with given tag added to its tags
+func withTag(metric plugin.MetricType, key, value string) plugin.MetricType {
+	tags := map[string]string{}
+	for k, v := range metric.Tags() {
+		tags[k] = v
+	}
+	tags[key] = value
+
+	metric.Tags_ = tags
+	return metric
+}
@@ -15,6 +15,10 @@ limitations under the License.
 package collector
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rackspace/gophercloud"
@@ -37,12 +41,20 @@ const (
 	plgtype = plugin.CollectorPluginType
 	vendor  = "intel"
 	fs      = "openstack"
+
+	// statusMetric is name of tenant metric reporting if tenant was collected successfully
+	statusMetric = "collection_success"
+	// providerTTL is time after which provider not used by any collection is evicted
+	providerTTL = 24 * time.Hour
 )
 
 // New creates initialized instance of Glance collector
 func New() *collector {
-	providers := map[string]*gophercloud.ProviderClient{}
-	return &collector{providers: providers}
+	providers := map[openstackintel.AuthOptions]*login{}
+	services := map[endpointKey]services.Service{}
+	snapshots := map[string]storedSnapshot{}
+	listings := map[endpointKey]listing{}
+	return &collector{providers: providers, services: services, snapshots: snapshots, listings: listings}
 }
 
 // GetMetricTypes returns list of available metric types
@@ -56,20 +68,101 @@ func (c *collector) GetMetricTypes(cfg plugin.ConfigType) ([]plugin.MetricType,
 		isTenantConfig = true
 	}
 
+	_, isRegional := configRegions(cfg)
+
+	// tenantNamespace returns namespace prefix for metrics collected per tenant
+	tenantNamespace := func() core.Namespace {
+		namespace := core.NewNamespace(vendor, fs, name)
+		if isRegional {
+			namespace = namespace.AddDynamicElement("region", "name of the region")
+		}
+		if isTenantConfig {
+			return namespace.AddStaticElement(tenantName.(string))
+		}
+		return namespace.AddDynamicElement("tenant", "name of the tenant")
+	}
+
 	imageTypes := []string{"private", "public", "shared"}
 	dataTypes := []string{"bytes", "count"}
 
 	for _, imageType := range imageTypes {
 		for _, dataType := range dataTypes {
-			namespace := core.NewNamespace(vendor, fs, name)
+			namespace := tenantNamespace().AddStaticElements("images", imageType, dataType)
 
-			if isTenantConfig {
-				namespace = namespace.AddStaticElement(tenantName.(string))
-			} else {
-				namespace = namespace.AddDynamicElement("tenant", "name of the tenant")
-			}
+			mts = append(mts, plugin.MetricType{
+				Namespace_: namespace,
+				Config_:    cfg.ConfigDataNode,
+			})
+		}
+	}
+
+	dupTypes := []string{"groups", "bytes", "largest_group"}
+
+	for _, dupType := range dupTypes {
+		namespace := tenantNamespace().AddStaticElements("images", "duplicates", dupType)
+
+		mts = append(mts, plugin.MetricType{
+			Namespace_: namespace,
+			Config_:    cfg.ConfigDataNode,
+		})
+
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendor, fs, name, "images", "duplicates", dupType),
+			Config_:    cfg.ConfigDataNode,
+		})
+	}
+
+	deltaTypes := []string{"created", "deleted", "status_changed", "bytes"}
+
+	for _, deltaType := range deltaTypes {
+		namespace := tenantNamespace().AddStaticElements("images", "delta", deltaType)
 
-			namespace = namespace.AddStaticElements("images", imageType, dataType)
+		mts = append(mts, plugin.MetricType{
+			Namespace_: namespace,
+			Config_:    cfg.ConfigDataNode,
+		})
+	}
+
+	for _, dataType := range dataTypes {
+		namespace := tenantNamespace().AddStaticElements("images", "unused", dataType)
+
+		mts = append(mts, plugin.MetricType{
+			Namespace_: namespace,
+			Config_:    cfg.ConfigDataNode,
+		})
+	}
+
+	volTypes := []string{"count", "bytes", "pending_delete"}
+
+	for _, volType := range volTypes {
+		namespace := tenantNamespace().AddStaticElements("images", "in_use_by_volumes", volType)
+
+		mts = append(mts, plugin.MetricType{
+			Namespace_: namespace,
+			Config_:    cfg.ConfigDataNode,
+		})
+	}
+
+	usageTypes := []string{"in_use_by_servers", "in_use_by_volumes"}
+
+	for _, usageType := range usageTypes {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: tenantNamespace().AddStaticElement("image").AddDynamicElement("image_id", "ID of the image").AddStaticElement(usageType),
+			Config_:    cfg.ConfigDataNode,
+		})
+	}
+
+	mts = append(mts, plugin.MetricType{
+		Namespace_: tenantNamespace().AddStaticElement("events").AddDynamicElement("event_type", "type of image event: created, deleted, visibility_changed or status_changed"),
+		Config_:    cfg.ConfigDataNode,
+	})
+
+	defTypes := []string{"private", "public"}
+	defDataTypes := []string{"namespaces", "properties", "objects", "resource_type_associations"}
+
+	for _, defType := range defTypes {
+		for _, dataType := range defDataTypes {
+			namespace := tenantNamespace().AddStaticElements("metadefs", defType, dataType)
 
 			mts = append(mts, plugin.MetricType{
 				Namespace_: namespace,
@@ -77,79 +170,595 @@ func (c *collector) GetMetricTypes(cfg plugin.ConfigType) ([]plugin.MetricType,
 			})
 		}
 	}
+
+	mts = append(mts, plugin.MetricType{
+		Namespace_: tenantNamespace().AddStaticElement(statusMetric),
+		Config_:    cfg.ConfigDataNode,
+	})
+
+	for _, probeType := range probeTypes {
+		for _, dataType := range []string{"status", "latency"} {
+			mts = append(mts, plugin.MetricType{
+				Namespace_: core.NewNamespace(vendor, fs, name, "probe", probeType, dataType),
+				Config_:    cfg.ConfigDataNode,
+			})
+		}
+	}
+
+	mts = append(mts, plugin.MetricType{
+		Namespace_: core.NewNamespace(vendor, fs, name, "probe", "available"),
+		Config_:    cfg.ConfigDataNode,
+	})
+
+	mts = append(mts, plugin.MetricType{
+		Namespace_: core.NewNamespace(vendor, fs, name, "api", "versions").
+			AddDynamicElement("version", "Glance API version, ex. v2.3").
+			AddStaticElement("status"),
+		Config_: cfg.ConfigDataNode,
+	})
+
+	mts = append(mts, plugin.MetricType{
+		Namespace_: core.NewNamespace(vendor, fs, name, "api", "selected"),
+		Config_:    cfg.ConfigDataNode,
+	})
+
+	statTypes := map[string][]string{
+		"http":     {"requests", "retries", "retries_exhausted"},
+		"keystone": {"logins", "login_duration"},
+		"glance":   {"listings", "listing_duration", "pages", "images"},
+		"cache":    {"hits", "misses"},
+		"errors":   {"timeout", "unauthorized", "not_found", "server_error", "other"},
+	}
+
+	for _, group := range selfGroups {
+		for _, statType := range statTypes[group] {
+			mts = append(mts, plugin.MetricType{
+				Namespace_: core.NewNamespace(vendor, fs, name, "collector", group, statType),
+				Config_:    cfg.ConfigDataNode,
+			})
+		}
+	}
 	return mts, nil
 }
 
 // CollectMetrics returns list of requested metric values
 // It returns error in case retrieval was not successful
 func (c *collector) CollectMetrics(metricTypes []plugin.MetricType) ([]plugin.MetricType, error) {
-	domain_name := ""
-	domain_id := ""
+	// get endpoint selection from configuration, credentials are read separately for each cloud
+	eo, err := endpointOpts(metricTypes[0])
+	if err != nil {
+		return nil, err
+	}
 
-	// get credentials and endpoint from configuration
-	items, err := config.GetConfigItems(metricTypes[0], "endpoint", "tenant", "user", "password")
+	// metrics calculated across all tenants and metrics of collector itself are emitted once, after tenant metrics are collected
+	tenantTypes := []plugin.MetricType{}
+	cloudTypes := []plugin.MetricType{}
+	selfTypes := []plugin.MetricType{}
+	healthTypes := []plugin.MetricType{}
+	apiTypes := []plugin.MetricType{}
+	for _, metricType := range metricTypes {
+		switch namespace := metricType.Namespace().Strings(); {
+		case isCloudMetric(namespace):
+			cloudTypes = append(cloudTypes, metricType)
+		case isProbeMetric(namespace):
+			healthTypes = append(healthTypes, metricType)
+		case isAPIMetric(namespace):
+			apiTypes = append(apiTypes, metricType)
+		case isCollectorMetric(namespace):
+			selfTypes = append(selfTypes, metricType)
+		default:
+			tenantTypes = append(tenantTypes, metricType)
+		}
+	}
+	listImages := len(cloudTypes) > 0
+
+	cacheTTL, err := configDuration(metricTypes[0], "cache_ttl")
 	if err != nil {
 		return nil, err
 	}
 
-	endpoint := items["endpoint"].(string)
-	tenant := items["tenant"].(string)
-	user := items["user"].(string)
-	password := items["password"].(string)
-	dom_name, _ := config.GetConfigItem(metricTypes[0], "domain_name")
-	dom_id, _ := config.GetConfigItem(metricTypes[0], "domain_id")
-	if dom_name != nil {
-		domain_name = dom_name.(string)
+	// Glance endpoints are probed while tenants are collected, if any probe metric is requested
+	var health *probes
+	if len(healthTypes) > 0 {
+		health = newProbes()
 	}
-	if dom_id != nil {
-		domain_id = dom_id.(string)
+	// Glance API versions are discovered while tenants are collected as well
+	var versions *apis
+	if len(apiTypes) > 0 {
+		versions = newAPIs()
 	}
+	endpoints := target{endpoint: eo, cacheTTL: cacheTTL, probes: health, apis: versions, servers: newUsages(), volumes: newUsages(), catalogs: newCatalogs()}
 
-	if err := c.authenticate(endpoint, tenant, user, password, domain_name, domain_id); err != nil {
-		return nil, err
+	var metrics []plugin.MetricType
+	var imgs listing
+	clouds := configClouds(metricTypes[0])
+	switch {
+	case len(tenantTypes) == 0 && !listImages && health == nil && versions == nil:
+		// only metrics of collector itself are requested, no tenant has to be logged in
+	case len(clouds) > 0:
+		metrics, imgs, err = c.collectClouds(metricTypes[0], clouds, endpoints, tenantTypes, listImages)
+	default:
+		var opts openstackintel.AuthOptions
+		opts, err = authOptions(metricTypes[0])
+		if err != nil {
+			return nil, err
+		}
+		t := endpoints
+		t.auth = opts
+		metrics, imgs, err = c.collectCloud(metricTypes[0], t, tenantTypes, listImages)
+	}
+	if err != nil {
+		// unavailable Glance is reported by probe metrics of endpoints probed before collection failed
+		metrics = []plugin.MetricType{}
+		for _, metricType := range healthTypes {
+			metrics = append(metrics, health.metrics(metricType)...)
+		}
+		if len(metrics) == 0 {
+			return nil, err
+		}
+		return metrics, nil
+	}
+
+	cloudContainer := cloudMetrics{
+		Images: cloudImagesMetrics{
+			Dup: findDuplicates(uniqueImages(imgs.images)),
+		},
+	}
+
+	for _, metricType := range cloudTypes {
+		metrics = append(metrics, withDataAge(plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: metricType.Namespace(),
+			Data_:      ns.GetValueByNamespace(cloudContainer, metricType.Namespace().Strings()[3:]),
+		}, imgs))
+	}
+
+	for _, metricType := range healthTypes {
+		metrics = append(metrics, health.metrics(metricType)...)
+	}
+
+	for _, metricType := range apiTypes {
+		metrics = append(metrics, versions.metrics(metricType)...)
 	}
 
-	provider := c.providers[tenant]
+	selfContainer := c.getStats()
+
+	for _, metricType := range selfTypes {
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: metricType.Namespace(),
+			Data_:      ns.GetValueByNamespace(selfContainer, metricType.Namespace().Strings()[4:]),
+		})
+	}
 
-	imgs, err := c.service.GetImages(provider)
+	return metrics, nil
+}
+
+// collectClouds collects tenant metrics from each of given clouds defined in clouds.yaml and tags them with name of the cloud
+// Cloud which cannot be collected is skipped, error is returned only if collection from all clouds failed.
+// Endpoint selection and collection state shared by clouds are given by endpoints target, without credentials.
+func (c *collector) collectClouds(cfg plugin.MetricType, clouds []string, endpoints target, metricTypes []plugin.MetricType, listImages bool) ([]plugin.MetricType, listing, error) {
+	metrics := []plugin.MetricType{}
+	imgs := listing{}
+	failures := []string{}
+	for _, cloud := range clouds {
+		// each cloud keeps its own snapshots of images, providers and dispatchers are kept by credentials of the cloud
+		t := endpoints
+		t.key = cloud
+
+		opts, err := cloudAuthOptions(cfg, cloud)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", cloud, err))
+			metrics = append(metrics, cloudFailureMetrics(cfg, t, cloud, metricTypes, err)...)
+			continue
+		}
+
+		t.auth = opts
+		mts, cloudImgs, err := c.collectCloud(cfg, t, metricTypes, listImages)
+		if err != nil {
+			// failed cloud is reported by status metrics, if requested
+			failures = append(failures, fmt.Sprintf("%s: %v", cloud, err))
+			metrics = append(metrics, cloudFailureMetrics(cfg, t, cloud, metricTypes, err)...)
+			continue
+		}
+
+		for _, mt := range mts {
+			metrics = append(metrics, withCloud(mt, cloud))
+		}
+		imgs = imgs.merge(cloudImgs)
+	}
+
+	if len(failures) == len(clouds) && len(metrics) == 0 {
+		return nil, listing{}, fmt.Errorf("Collection from all clouds failed: %s", strings.Join(failures, "; "))
+	}
+
+	return metrics, imgs, nil
+}
+
+// cloudFailureMetrics returns status metrics of cloud which could not be collected, tagged with name of the cloud.
+// Dynamic tenant element is set to tenant of the cloud if it is known, as tenants of failed cloud cannot be discovered.
+func cloudFailureMetrics(cfg plugin.MetricType, t target, cloud string, metricTypes []plugin.MetricType, err error) []plugin.MetricType {
+	tenant := t.auth.TenantName
+	if tenant == "" {
+		tenant = configString(cfg, "tenant")
+	}
+	requested := metricTypes
+	if tenant != "" {
+		requested = tenantMetricTypes(t, tenant, metricTypes, tenantIndex(cfg))
+	}
+
+	metrics := []plugin.MetricType{}
+	for _, mt := range failureMetrics(regionMetricTypes(cfg, requested), err) {
+		metrics = append(metrics, withCloud(mt, cloud))
+	}
+	return metrics
+}
+
+// collectCloud collects tenant metrics from single cloud, logging in and listing images of at most max_concurrency
+// tenants at once. Metrics are returned in order of tenant names. Tenant which cannot be collected is reported
+// by its status metric, error is returned only if collection from all tenants failed and no status was requested.
+func (c *collector) collectCloud(cfg plugin.MetricType, t target, metricTypes []plugin.MetricType, listImages bool) ([]plugin.MetricType, listing, error) {
+	workers, err := configInt(cfg, "max_concurrency")
 	if err != nil {
-		return nil, err
+		return nil, listing{}, err
+	}
+	if workers == 0 {
+		workers = 1
+	}
+
+	index := tenantIndex(cfg)
+	var names []string
+	if len(metricTypes) == 0 && !listImages {
+		// Glance endpoints are probed and their API versions are read once per endpoint, so single tenant is enough
+		names, err = c.endpointTenant(t, index)
+	} else {
+		names, err = tenants(t, metricTypes, index)
+	}
+	if err != nil {
+		return nil, listing{}, err
 	}
 
+	requested := make([][]plugin.MetricType, len(names))
+	results := make([][]plugin.MetricType, len(names))
+	listings := make([]listing, len(names))
+	errs := make([]error, len(names))
+	forEach(len(names), workers, func(i int) {
+		requested[i] = tenantMetricTypes(t, names[i], metricTypes, index)
+		results[i], listings[i], errs[i] = c.collectTenantOf(cfg, tenantTarget(t, names[i]), requested[i], listImages)
+	})
+
 	metrics := []plugin.MetricType{}
+	imgs := listing{}
+	failures := []string{}
+	for i, tenant := range names {
+		if errs[i] != nil {
+			// failed tenant is reported by its status metric, if requested
+			failures = append(failures, fmt.Sprintf("%s: %v", tenant, errs[i]))
+			metrics = append(metrics, failureMetrics(regionMetricTypes(cfg, requested[i]), errs[i])...)
+			continue
+		}
+		metrics = append(metrics, results[i]...)
+		imgs = imgs.merge(listings[i])
+	}
+
+	if len(failures) == len(names) && len(metrics) == 0 {
+		// error of the only tenant is returned unchanged
+		if len(names) == 1 {
+			return nil, listing{}, errs[0]
+		}
+		return nil, listing{}, fmt.Errorf("Collection from all tenants failed: %s", strings.Join(failures, "; "))
+	}
+
+	return metrics, imgs, nil
+}
+
+// collectTenantOf collects metrics of single tenant, in each configured region if multi-region collection is enabled
+func (c *collector) collectTenantOf(cfg plugin.MetricType, t target, metricTypes []plugin.MetricType, listImages bool) ([]plugin.MetricType, listing, error) {
+	collect := func(retried bool) ([]plugin.MetricType, listing, error) {
+		provider, err := c.authenticate(t.auth)
+		if err != nil {
+			return nil, listing{}, err
+		}
+
+		if regions, found := configRegions(cfg); found {
+			return c.collectRegions(provider, t, regions, metricTypes, listImages, retried)
+		}
+		return c.collectTenant(provider, t, metricTypes, listImages)
+	}
+
+	metrics, imgs, err := collect(false)
+	if _, stale := err.(*staleError); stale {
+		// session expired, project was removed or negotiated Glance API version is not available anymore,
+		// so client logs in again and negotiates Glance API version again, but only once per collection
+		c.invalidate(t.auth)
+		metrics, imgs, err = collect(true)
+	}
+
+	return metrics, imgs, err
+}
+
+// collectRegions collects tenant metrics from Glance in each of given regions, or in each region found
+// in service catalog if no region is given. Region element is removed from namespace of requested metrics
+// before collection and set in namespace of collected metrics. Region which cannot be collected is reported
+// by its status metric, stale session is returned to be renewed unless collection is already retried.
+func (c *collector) collectRegions(provider *gophercloud.ProviderClient, t target, regions []string, metricTypes []plugin.MetricType, listImages bool, retried bool) ([]plugin.MetricType, listing, error) {
+	var err error
+	if len(regions) == 0 {
+		regions, err = openstackintel.Common{Endpoint: t.endpoint}.GetRegions(provider)
+		if err != nil {
+			return nil, listing{}, err
+		}
+	}
+
+	requested := map[string][]plugin.MetricType{}
 	for _, metricType := range metricTypes {
-		namespace := metricType.Namespace().Strings()
-		// Construct temporary struct to generate namespace based on tags
-		metricContainer := struct {
-			I struct {
-				Prv types.Images `json:"private"`
-				Pub types.Images `json:"public"`
-				Sha types.Images `json:"shared"`
-			} `json:"images"`
-		}{
-			struct {
-				Prv types.Images `json:"private"`
-				Pub types.Images `json:"public"`
-				Sha types.Images `json:"shared"`
-			}{imgs["private"], imgs["public"], imgs["shared"]},
+		value := metricType.Namespace().Element(3).Value
+		for _, region := range regions {
+			if value == "*" || value == region {
+				requested[region] = append(requested[region], withoutRegion(metricType))
+			}
+		}
+	}
+
+	metrics := []plugin.MetricType{}
+	imgs := listing{}
+	collected := 0
+	failures := []string{}
+	var regionErr error
+	for _, region := range regions {
+		if len(requested[region]) == 0 && !listImages && t.probes == nil && t.apis == nil {
+			continue
+		}
+		collected++
+
+		// each region keeps its own dispatcher and snapshot of images
+		regionTarget := t
+		regionTarget.key = t.key + "/" + region
+		regionTarget.endpoint.Region = region
+
+		mts, regionImgs, err := c.collectTenant(provider, regionTarget, requested[region], listImages)
+		if err != nil {
+			err = regionError(region, err)
+			if _, stale := err.(*staleError); stale && !retried {
+				return nil, listing{}, err
+			}
+			// failed region is reported by its status metric, if requested
+			failures = append(failures, err.Error())
+			regionErr = err
+			mts = failureMetrics(requested[region], err)
+		}
+
+		for _, mt := range mts {
+			metrics = append(metrics, withRegion(mt, region))
+		}
+		imgs = imgs.merge(regionImgs)
+	}
+
+	if collected > 0 && len(failures) == collected && len(metrics) == 0 {
+		// error of the only region is returned unchanged
+		if collected == 1 {
+			return nil, listing{}, regionErr
+		}
+		return nil, listing{}, fmt.Errorf("Collection from all regions failed: %s", strings.Join(failures, "; "))
+	}
+
+	return metrics, imgs, nil
+}
+
+// regionMetricTypes returns metric types with dynamic region element replaced by each of configured regions,
+// so that failure of tenant is reported in each region. Metric types are returned unchanged if regions are
+// discovered in service catalog or multi-region collection is not enabled.
+func regionMetricTypes(cfg plugin.MetricType, metricTypes []plugin.MetricType) []plugin.MetricType {
+	regions, found := configRegions(cfg)
+	if !found || len(regions) == 0 {
+		return metricTypes
+	}
+
+	expanded := []plugin.MetricType{}
+	for _, metricType := range metricTypes {
+		value := metricType.Namespace().Element(3).Value
+		for _, region := range regions {
+			if value == "*" || value == region {
+				expanded = append(expanded, withRegion(withoutRegion(metricType), region))
+			}
 		}
+	}
+	return expanded
+}
+
+// collectTenant collects tenant metrics from single Glance endpoint
+// Images are listed also when no images metric is requested, but listImages is set
+func (c *collector) collectTenant(provider *gophercloud.ProviderClient, t target, metricTypes []plugin.MetricType, listImages bool) ([]plugin.MetricType, listing, error) {
+	common := openstackintel.Common{Endpoint: t.endpoint}
+
+	// Glance is probed before its API version is negotiated, so that unavailable endpoint is still reported
+	if t.probes != nil {
+		client, err := openstackintel.NewImageService(provider, t.endpoint)
+		if err != nil {
+			return nil, listing{}, err
+		}
+		if t.probes.claim(client.Endpoint) {
+			url, results, err := common.ProbeGlance(provider, t.auth.Transport)
+			if err != nil {
+				return nil, listing{}, err
+			}
+			t.probes.set(url, results)
+		}
+	}
+
+	service, err := c.dispatch(endpointKey{auth: t.auth, endpoint: t.endpoint}, provider)
+	if err != nil {
+		return nil, listing{}, checkStale(err)
+	}
+
+	if t.apis != nil && t.apis.claim(service.URL) {
+		available, err := common.GetApiVersions(provider)
+		if err != nil {
+			return nil, listing{}, checkStale(err)
+		}
+		t.apis.set(service.URL, service.Version, available)
+	}
+
+	var list listing
+	var counts map[string]types.Images
+	if listImages || isRequested(metricTypes, "images") || isRequested(metricTypes, "image") || isRequested(metricTypes, "events") {
+		list, err = c.listImages(service, provider, t)
+		if err != nil {
+			return nil, listing{}, checkStale(err)
+		}
+
+		counts, err = openstackintel.CountImages(list.images)
+		if err != nil {
+			return nil, listing{}, err
+		}
+	}
+	imgs := list.images
+
+	var serversUsage map[string]int
+	if isRequested(metricTypes, "images", "unused") || isUsageRequested(metricTypes, "in_use_by_servers") {
+		var client *gophercloud.ServiceClient
+		client, err = openstackintel.NewComputeService(provider, t.endpoint.EndpointOpts)
+		if err != nil {
+			return nil, listing{}, err
+		}
+		serversUsage, err = t.servers.get(client.Endpoint, func() (map[string]int, error) {
+			return common.GetServersUsage(provider)
+		})
+		if err != nil {
+			return nil, listing{}, err
+		}
+	}
+
+	var volumesUsage map[string]int
+	if isRequested(metricTypes, "images", "in_use_by_volumes") || isUsageRequested(metricTypes, "in_use_by_volumes") {
+		var client *gophercloud.ServiceClient
+		client, err = openstackintel.NewBlockStorageService(provider, t.endpoint.EndpointOpts)
+		if err != nil {
+			return nil, listing{}, err
+		}
+		volumesUsage, err = t.volumes.get(client.Endpoint, func() (map[string]int, error) {
+			return common.GetVolumesUsage(provider)
+		})
+		if err != nil {
+			return nil, listing{}, err
+		}
+	}
+
+	// metadefs may be disabled or not deployed, so their errors neither invalidate negotiated version
+	// nor fail collection of other metrics, failure is reported by status metric instead
+	var defs map[string]types.Metadefs
+	var defsErr error
+	if isRequested(metricTypes, "metadefs") {
+		defs, defsErr = t.catalogs.get(service.URL, func() (map[string]types.Metadefs, error) {
+			return service.GetMetadefs(provider)
+		})
+		if defsErr != nil {
+			defsErr = fmt.Errorf("Metadata definitions could not be collected: %v", defsErr)
+		}
+	}
+
+	// snapshot is advanced only after all requests succeeded, so no image change is lost
+	var delta types.Delta
+	events := []imageEvent{}
+	if isRequested(metricTypes, "images", "delta") || isRequested(metricTypes, "events") {
+		key := snapshotKey(t.key, metricTypes)
+		delta, events = c.advanceSnapshot(key, list)
+	}
+
+	// Construct temporary structs to generate namespace based on tags
+	tenantContainer := tenantMetrics{
+		Images: imagesMetrics{
+			Prv: counts["private"],
+			Pub: counts["public"],
+			Sha: counts["shared"],
+			Dup: findDuplicates(imgs),
+			Del: delta,
+			Unu: unusedImages(imgs, serversUsage),
+			Vol: volumeImages(imgs, volumesUsage),
+		},
+		Metadefs: metadefsMetrics{
+			Prv: defs["private"],
+			Pub: defs["public"],
+		},
+	}
+
+	metrics := []plugin.MetricType{}
+	for _, metricType := range metricTypes {
+		namespace := metricType.Namespace().Strings()
 
 		// Extract values by namespace from temporary struct and create metrics
 		metric := plugin.MetricType{
 			Timestamp_: time.Now(),
 			Namespace_: metricType.Namespace(),
-			Data_:      ns.GetValueByNamespace(metricContainer, namespace[4:]),
 		}
+
+		// tenant metrics are returned only if all of them were collected, except metadefs metrics
+		if namespace[4] == statusMetric {
+			metric.Data_ = 1
+			if defsErr != nil {
+				metric.Data_ = 0
+				metric.Tags_ = map[string]string{"error": defsErr.Error()}
+			}
+			metrics = append(metrics, metric)
+			continue
+		}
+
+		// metadefs metrics are skipped if catalog is not available, ex. in Glance API v1
+		if namespace[4] == "metadefs" && defs == nil {
+			continue
+		}
+
+		// single metric is emitted for each image event matching requested event type
+		if namespace[4] == "events" {
+			for _, event := range eventMetrics(metricType, events) {
+				metrics = append(metrics, withDataAge(event, list))
+			}
+			continue
+		}
+
+		// single metric is emitted for each image matching requested image ID
+		if namespace[4] == "image" {
+			usage := serversUsage
+			if namespace[6] == "in_use_by_volumes" {
+				usage = volumesUsage
+			}
+			for _, mt := range usageMetrics(metricType, imgs, usage) {
+				metrics = append(metrics, withDataAge(mt, list))
+			}
+			continue
+		}
+
+		metric.Data_ = ns.GetValueByNamespace(tenantContainer, namespace[4:])
+
+		// images metrics are tagged with age of image listing, which may be taken from cache
+		if namespace[4] == "images" {
+			metric = withDataAge(metric, list)
+		}
+
+		// metadefs metrics are tagged with names of namespaces to allow catalog comparison
+		if namespace[4] == "metadefs" {
+			metric.Tags_ = map[string]string{
+				"namespaces": strings.Join(defs[namespace[5]].Names, ","),
+			}
+		}
+
 		metrics = append(metrics, metric)
 	}
 
-	return metrics, nil
+	return metrics, list, nil
 }
 
 // GetConfigPolicy returns config policy
 // It returns error in case retrieval was not successful
 func (c *collector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	cp := cpolicy.New()
+
+	node, err := configPolicy()
+	if err != nil {
+		return nil, err
+	}
+	cp.Add([]string{vendor, fs, name}, node)
+
 	return cp, nil
 }
 
@@ -165,25 +774,283 @@ func Meta() *plugin.PluginMeta {
 	)
 }
 
+// tenantMetrics is used to generate metrics namespace for tenant metrics based on tags
+type tenantMetrics struct {
+	Images   imagesMetrics   `json:"images"`
+	Metadefs metadefsMetrics `json:"metadefs"`
+}
+
+type imagesMetrics struct {
+	Prv types.Images       `json:"private"`
+	Pub types.Images       `json:"public"`
+	Sha types.Images       `json:"shared"`
+	Dup types.Duplicates   `json:"duplicates"`
+	Del types.Delta        `json:"delta"`
+	Unu types.Images       `json:"unused"`
+	Vol types.VolumeImages `json:"in_use_by_volumes"`
+}
+
+type metadefsMetrics struct {
+	Prv types.Metadefs `json:"private"`
+	Pub types.Metadefs `json:"public"`
+}
+
+// cloudMetrics is used to generate metrics namespace for metrics calculated across all tenants
+type cloudMetrics struct {
+	Images cloudImagesMetrics `json:"images"`
+}
+
+type cloudImagesMetrics struct {
+	Dup types.Duplicates `json:"duplicates"`
+}
+
 type collector struct {
-	service   services.Service
-	common    openstackintel.Commoner
-	providers map[string]*gophercloud.ProviderClient
+	// mutex guards maps below, as Snap may collect metrics of several tasks at once
+	// Requests to OpenStack are sent without holding the mutex.
+	mutex     sync.Mutex
+	providers map[openstackintel.AuthOptions]*login
+	services  map[endpointKey]services.Service
+	snapshots map[string]storedSnapshot
+	listings  map[endpointKey]listing
+	// stats holds metrics of collector itself, except counters kept by openstack package
+	stats collectorMetrics
 }
 
-func (c *collector) authenticate(endpoint, tenant, user, password, domain_name, domain_id string) error {
-	if _, found := c.providers[tenant]; !found {
-		provider, err := openstackintel.Authenticate(endpoint, user, password, tenant, domain_name, domain_id)
-		if err != nil {
-			return err
+// target identifies tenant and Glance endpoint to collect metrics from
+type target struct {
+	// key identifies snapshots of images, ex. tenant or cloud, tenant and region, it is empty
+	// or name of the cloud before tenants are selected. Providers and dispatchers are identified
+	// by auth and endpoint options, so tenants of the same name in different clouds do not share them.
+	key      string
+	auth     openstackintel.AuthOptions
+	endpoint openstackintel.EndpointOpts
+	// cacheTTL is maximal age of cached image listing, zero disables cache
+	cacheTTL time.Duration
+	// probes collects results of health probes of Glance endpoints, nil if probes are not requested
+	probes *probes
+	// apis collects Glance API versions of Glance endpoints, nil if version metrics are not requested
+	apis *apis
+	// servers shares usage of images by servers of all tenants between tenants using the same Nova endpoint
+	servers *usages
+	// volumes shares usage of images by volumes of all tenants between tenants using the same Cinder endpoint
+	volumes *usages
+	// catalogs shares metadata definitions catalog between tenants using the same Glance endpoint
+	catalogs *catalogs
+}
+
+// login is authentication of provider with single set of options, shared by collections which need it at once
+type login struct {
+	once     sync.Once
+	provider *gophercloud.ProviderClient
+	err      error
+	// usedAt is time of the last collection which used the provider
+	usedAt time.Time
+}
+
+// endpointKey identifies Glance endpoint reached with given credentials, dispatchers and image listings are cached by it
+type endpointKey struct {
+	auth     openstackintel.AuthOptions
+	endpoint openstackintel.EndpointOpts
+}
+
+// authenticate returns provider authenticated with given options. Provider is created only once for given options,
+// collections which need it at once wait for the same login. Failed login is not kept, so it is attempted again
+// by next collection. Providers not used within providerTTL, ex. of rotated password, are evicted.
+func (c *collector) authenticate(opts openstackintel.AuthOptions) (*gophercloud.ProviderClient, error) {
+	now := time.Now()
+
+	c.mutex.Lock()
+	entry, found := c.providers[opts]
+	if !found {
+		entry = &login{}
+		c.providers[opts] = entry
+	}
+	entry.usedAt = now
+	for auth, other := range c.providers {
+		if now.Sub(other.usedAt) > providerTTL {
+			c.invalidateLocked(auth)
+		}
+	}
+	c.mutex.Unlock()
+
+	entry.once.Do(func() {
+		started := time.Now()
+		entry.provider, entry.err = openstackintel.Authenticate(opts)
+		c.countLogin(started, entry.err)
+	})
+	if entry.err != nil {
+		c.mutex.Lock()
+		if c.providers[opts] == entry {
+			c.invalidateLocked(opts)
+		}
+		c.mutex.Unlock()
+		return nil, entry.err
+	}
+
+	return entry.provider, nil
+}
+
+// endpointTenant returns single tenant to reach Glance endpoints with, when no tenant metric is requested.
+// Tenant of provider kept for the same credentials is preferred, so no tenant is discovered or logged in again.
+func (c *collector) endpointTenant(t target, index int) ([]string, error) {
+	if t.auth.TenantName == "" && t.auth.TenantID == "" && !t.auth.IsProjectBound() {
+		if tenant, found := c.loggedInTenant(t.auth); found {
+			return []string{tenant}, nil
+		}
+	}
+
+	names, err := tenants(t, nil, index)
+	if err != nil {
+		return nil, err
+	}
+	return names[:1], nil
+}
+
+// loggedInTenant returns name of the first tenant, which provider authenticated with given credentials is kept for
+func (c *collector) loggedInTenant(opts openstackintel.AuthOptions) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	names := []string{}
+	for auth := range c.providers {
+		tenant := auth.TenantName
+		auth.TenantName = ""
+		auth.TenantID = ""
+		if auth == opts && tenant != "" {
+			names = append(names, tenant)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+// invalidate removes provider authenticated with given options together with dispatchers and image listings
+// which depend on it, so they are created again during next collection
+func (c *collector) invalidate(auth openstackintel.AuthOptions) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.invalidateLocked(auth)
+}
+
+// invalidateLocked removes provider like invalidate, it must be called with mutex held
+func (c *collector) invalidateLocked(auth openstackintel.AuthOptions) {
+	delete(c.providers, auth)
+	for key := range c.listings {
+		if key.auth == auth {
+			delete(c.listings, key)
 		}
-		// set provider and dispatch API version based on priority
-		c.providers[tenant] = provider
-		c.service = services.Dispatch(provider)
+	}
+	for key := range c.services {
+		if key.auth == auth {
+			delete(c.services, key)
+		}
+	}
+}
+
+// dispatch returns dispatcher of Glance API version negotiated for given credentials and endpoint options,
+// ex. region. Version is negotiated only once for each of them, failed negotiation is retried during next collection.
+func (c *collector) dispatch(key endpointKey, provider *gophercloud.ProviderClient) (services.Service, error) {
+	c.mutex.Lock()
+	service, found := c.services[key]
+	c.mutex.Unlock()
 
-		// set Commoner interface
-		c.common = openstackintel.Common{}
+	if found {
+		return service, nil
 	}
 
-	return nil
+	service, err := services.Dispatch(provider, key.endpoint)
+	if err != nil {
+		c.mutex.Lock()
+		c.countError(err)
+		c.mutex.Unlock()
+		return service, err
+	}
+
+	c.mutex.Lock()
+	c.services[key] = service
+	c.mutex.Unlock()
+
+	return service, nil
+}
+
+// isRequested checks if any of requested metrics belongs to given group, ex. images or images/delta
+func isRequested(metricTypes []plugin.MetricType, group ...string) bool {
+	for _, metricType := range metricTypes {
+		namespace := metricType.Namespace().Strings()
+		if isCloudMetric(namespace) {
+			namespace = namespace[3:]
+		} else if len(namespace) > 4 {
+			namespace = namespace[4:]
+		} else {
+			continue
+		}
+
+		if len(namespace) < len(group) {
+			continue
+		}
+
+		matched := true
+		for i := range group {
+			if namespace[i] != group[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isUsageRequested checks if any of requested per image metrics is of given usage type, ex. in_use_by_servers
+func isUsageRequested(metricTypes []plugin.MetricType, usageType string) bool {
+	for _, metricType := range metricTypes {
+		namespace := metricType.Namespace().Strings()
+		if len(namespace) == 7 && namespace[4] == "image" && namespace[6] == usageType {
+			return true
+		}
+	}
+	return false
+}
+
+// failureMetrics returns status metrics of failed collection, tagged with its error, for requested status metric types
+func failureMetrics(metricTypes []plugin.MetricType, err error) []plugin.MetricType {
+	metrics := []plugin.MetricType{}
+	for _, metricType := range metricTypes {
+		namespace := metricType.Namespace().Strings()
+		if namespace[len(namespace)-1] != statusMetric {
+			continue
+		}
+
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: metricType.Namespace(),
+			Data_:      0,
+			Tags_:      map[string]string{"error": err.Error()},
+		})
+	}
+	return metrics
+}
+
+// isCloudMetric checks if metric is calculated across all tenants, ex. /intel/openstack/glance/images/duplicates/groups
+func isCloudMetric(namespace []string) bool {
+	return len(namespace) == 6 && namespace[3] == "images"
+}
+
+// isCollectorMetric checks if metric describes collector itself, ex. /intel/openstack/glance/collector/http/retries
+func isCollectorMetric(namespace []string) bool {
+	if len(namespace) != 6 || namespace[3] != "collector" {
+		return false
+	}
+	for _, group := range selfGroups {
+		if namespace[4] == group {
+			return true
+		}
+	}
+	return false
 }
@@ -16,11 +16,21 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rackspace/gophercloud"
 	th "github.com/rackspace/gophercloud/testhelper"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/suite"
@@ -31,6 +41,9 @@ import (
 	"github.com/intelsdi-x/snap/core/ctypes"
 
 	"github.com/intelsdi-x/snap-plugin-utilities/str"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
 )
 
 type CollectorSuite struct {
@@ -40,123 +53,1957 @@ type CollectorSuite struct {
 	Tenant1, Tenant2   string
 	Images             string
 	Img1Size, Img2Size int
-	Server             *httptest.Server
+	// ImageRequests is number of image listings served by Glance
+	ImageRequests int64
+	// ServerRequests is number of server listings served by Nova
+	ServerRequests int64
+	// VolumeRequests is number of volume listings served by Cinder
+	VolumeRequests int64
+	// MetadefsRequests is number of metadata definitions namespace listings served by Glance
+	MetadefsRequests int64
+	// UnavailableLogins is number of following Keystone v2 logins which fail with 503
+	UnavailableLogins int64
+	Server            *httptest.Server
+	BlockStorage      *httptest.Server
+}
+
+func (s *CollectorSuite) SetupSuite() {
+	// for glance calls
+	th.SetupHTTP()
+
+	// for identity calls
+	router := mux.NewRouter()
+	s.Server = httptest.NewServer(router)
+
+	// for cinder calls
+	s.BlockStorage = httptest.NewServer(registerBlockStorageVolumes(s))
+
+	registerIdentityRoot(s, router)
+	registerIdentityTokens(s, router)
+	registerIdentityTenants(s, router, "demo", "admin")
+	registerIdentityCatalog(s, router)
+	registerGlanceApi(s)
+	registerGlanceImages(s, 1000, 2000)
+	registerGlanceMetadefs(s)
+	registerComputeServers(s)
+}
+
+func (s *CollectorSuite) TearDownSuite() {
+	th.TeardownHTTP()
+	s.BlockStorage.Close()
+}
+
+func (s *CollectorSuite) TestGetMetricTypesStar() {
+	Convey("Given config with enpoint, user and password defined", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+
+		Convey("When GetMetricTypes() is called", func() {
+			collector := New()
+			mts, err := collector.GetMetricTypes(cfg)
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and proper metric types are returned", func() {
+				metricNames := []string{}
+				for _, m := range mts {
+					metricNames = append(metricNames, m.Namespace().String())
+				}
+
+				So(len(mts), ShouldEqual, 58)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/private/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/public/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/collection_success"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/collector/keystone/login_duration"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/probe/available"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/api/versions/*/status"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/shared/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/private/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/public/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/shared/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/duplicates/groups"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/duplicates/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/duplicates/largest_group"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/groups"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/largest_group"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/delta/created"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/delta/deleted"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/delta/status_changed"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/delta/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/events/*"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/unused/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/unused/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/image/*/in_use_by_servers"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/in_use_by_volumes/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/in_use_by_volumes/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/in_use_by_volumes/pending_delete"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/image/*/in_use_by_volumes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/public/namespaces"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/public/properties"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/public/objects"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/public/resource_type_associations"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/private/namespaces"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/private/properties"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/private/objects"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/metadefs/private/resource_type_associations"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestGetMetricTypesTenant() {
+	Convey("Given config with enpoint, user and password defined", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+
+		Convey("When GetMetricTypes() is called", func() {
+			collector := New()
+			mts, err := collector.GetMetricTypes(cfg)
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and proper metric types are returned", func() {
+				metricNames := []string{}
+				for _, m := range mts {
+					metricNames = append(metricNames, m.Namespace().String())
+				}
+
+				So(len(mts), ShouldEqual, 58)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/private/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/public/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/shared/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/private/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/public/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/shared/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/duplicates/groups"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/duplicates/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/duplicates/largest_group"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/groups"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/largest_group"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/delta/created"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/delta/deleted"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/delta/status_changed"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/delta/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/events/*"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/unused/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/unused/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/image/*/in_use_by_servers"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/in_use_by_volumes/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/in_use_by_volumes/bytes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/in_use_by_volumes/pending_delete"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/image/*/in_use_by_volumes"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/public/namespaces"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/public/properties"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/public/objects"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/public/resource_type_associations"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/private/namespaces"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/private/properties"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/private/objects"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/metadefs/private/resource_type_associations"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestGetConfigPolicy() {
+	Convey("Given config policy of the plugin", s.T(), func() {
+		policy, err := New().GetConfigPolicy()
+		So(err, ShouldBeNil)
+		node := policy.Get([]string{"intel", "openstack", "glance"})
+		So(node, ShouldNotBeNil)
+
+		Convey("When valid config is processed", func() {
+			cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+			processed, errs := node.Process(cfg.Table())
+
+			Convey("Then defaults are added and metrics can be collected with processed config", func() {
+				So(errs.HasErrors(), ShouldBeFalse)
+				So((*processed)["max_concurrency"], ShouldResemble, ctypes.ConfigValueStr{Value: "1"})
+				So((*processed)["max_attempts"], ShouldResemble, ctypes.ConfigValueStr{Value: "3"})
+				_, found := (*processed)["regions"]
+				So(found, ShouldBeFalse)
+
+				processedCfg := cdata.NewNode()
+				for key, value := range *processed {
+					processedCfg.AddItem(key, value)
+				}
+				mts, err := New().CollectMetrics([]plugin.MetricType{{
+					Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+					Config_:    processedCfg}})
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When option has invalid type", func() {
+			cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+			cfg.AddItem("user", ctypes.ConfigValueInt{Value: 1})
+			_, errs := node.Process(cfg.Table())
+
+			Convey("Then config is rejected", func() {
+				So(errs.HasErrors(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetrics() {
+	Convey("Given set of metric types", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "bytes"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When ColelctMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and proper metric types are returned", func() {
+				metricNames := map[string]interface{}{}
+				for _, m := range mts {
+					ns := m.Namespace().String()
+					metricNames[ns] = m.Data()
+				}
+				fmt.Println(metricNames)
+				So(len(mts), ShouldEqual, 2)
+
+				val, ok := metricNames["/intel/openstack/glance/tenant/images/public/count"]
+				So(ok, ShouldBeTrue)
+				So(val, ShouldEqual, 2)
+
+				val, ok = metricNames["/intel/openstack/glance/tenant/images/public/bytes"]
+				So(ok, ShouldBeTrue)
+				So(val, ShouldEqual, s.Img1Size+s.Img2Size)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsInvalidAuthOptions() {
+	Convey("Given application credential mixed with password", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("application_credential_id", ctypes.ConfigValueStr{Value: "423f19a4ac1e4f48bbb4180756e6eb6c"})
+		cfg.AddItem("application_credential_secret", ctypes.ConfigValueStr{Value: "rEaqvJka48mpv"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			_, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error describing invalid options is reported", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "application credential")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsFromCloud() {
+	Convey("Given cloud defined in clouds.yaml", s.T(), func() {
+		dir, err := ioutil.TempDir("", "clouds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cloudsFile := filepath.Join(dir, "clouds.yaml")
+		err = ioutil.WriteFile(cloudsFile, []byte(fmt.Sprintf(`
+clouds:
+  test:
+    auth:
+      auth_url: %s
+      username: me
+      password: secret
+      project_name: tenant
+    verify: false
+`, s.Server.URL)), 0600)
+		So(err, ShouldBeNil)
+
+		node := cdata.NewNode()
+		node.AddItem("cloud", ctypes.ConfigValueStr{Value: "test"})
+		node.AddItem("clouds_file", ctypes.ConfigValueStr{Value: cloudsFile})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    node}
+
+		Convey("When OS_* environment variables describe other auth method", func() {
+			os.Setenv("OS_APPLICATION_CREDENTIAL_ID", "423f19a4ac1e4f48bbb4180756e6eb6c")
+			os.Setenv("OS_APPLICATION_CREDENTIAL_SECRET", "rEaqvJka48mpv")
+			defer os.Unsetenv("OS_APPLICATION_CREDENTIAL_ID")
+			defer os.Unsetenv("OS_APPLICATION_CREDENTIAL_SECRET")
+
+			opts, err := authOptions(m1)
+
+			Convey("Then environment is not merged into cloud entry", func() {
+				So(err, ShouldBeNil)
+				So(opts.Username, ShouldEqual, "me")
+				So(opts.ApplicationCredentialID, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When insecure is not configured", func() {
+			opts, err := authOptions(m1)
+
+			Convey("Then verify of cloud entry is used", func() {
+				So(err, ShouldBeNil)
+				So(opts.Transport.Insecure, ShouldBeTrue)
+			})
+		})
+
+		Convey("When insecure is disabled in configuration", func() {
+			node.AddItem("insecure", ctypes.ConfigValueStr{Value: "false"})
+			opts, err := authOptions(m1)
+
+			Convey("Then it overrides verify of cloud entry", func() {
+				So(err, ShouldBeNil)
+				So(opts.Transport.Insecure, ShouldBeFalse)
+			})
+		})
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then credentials are read from clouds.yaml", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsFromClouds() {
+	Convey("Given several clouds defined in clouds.yaml", s.T(), func() {
+		dir, err := ioutil.TempDir("", "clouds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cloudsFile := filepath.Join(dir, "clouds.yaml")
+		err = ioutil.WriteFile(cloudsFile, []byte(fmt.Sprintf(`
+clouds:
+  first:
+    auth:
+      auth_url: %s
+      username: me
+      password: secret
+      project_name: tenant
+  second:
+    auth:
+      auth_url: %s
+      username: me
+      password: secret
+      project_name: tenant
+  broken:
+    auth:
+      auth_url: %s
+      username: me
+`, s.Server.URL, s.Server.URL, s.Server.URL)), 0600)
+		So(err, ShouldBeNil)
+
+		node := cdata.NewNode()
+		node.AddItem("clouds_file", ctypes.ConfigValueStr{Value: cloudsFile})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    node}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "images", "duplicates", "groups"),
+			Config_:    node}
+
+		Convey("When CollectMetrics() is called", func() {
+			node.AddItem("clouds", ctypes.ConfigValueStr{Value: "first, broken, second"})
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then metrics of each available cloud are tagged with its name", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 3)
+				So(mts[0].Tags()["cloud"], ShouldEqual, "first")
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Tags()["cloud"], ShouldEqual, "second")
+				So(mts[1].Data(), ShouldEqual, 2)
+				So(mts[2].Namespace().String(), ShouldEqual, "/intel/openstack/glance/images/duplicates/groups")
+			})
+		})
+
+		Convey("When status of clouds is requested", func() {
+			node.AddItem("clouds", ctypes.ConfigValueStr{Value: "first, broken"})
+			m3 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("tenant", "name of the tenant").
+					AddStaticElement("collection_success"),
+				Config_: node}
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m3})
+
+			Convey("Then failed cloud is reported by its status tagged with cloud and error", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 3)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/tenant/collection_success")
+				So(mts[1].Data(), ShouldEqual, 1)
+				So(mts[1].Tags()["cloud"], ShouldEqual, "first")
+				So(mts[2].Data(), ShouldEqual, 0)
+				So(mts[2].Tags()["cloud"], ShouldEqual, "broken")
+				So(mts[2].Tags()["error"], ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When none of clouds can be collected", func() {
+			node.AddItem("clouds", ctypes.ConfigValueStr{Value: "broken, missing"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error names each cloud", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "broken")
+				So(err.Error(), ShouldContainSubstring, "missing")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsEndpointSelection() {
+	Convey("Given region which is not available in service catalog", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("region", ctypes.ConfigValueStr{Value: "RegionTwo"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When endpoint_override is set", func() {
+			cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: th.Endpoint()})
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then service catalog is skipped", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given unknown interface", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("interface", ctypes.ConfigValueStr{Value: "private"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "interface")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsTimeout() {
+	Convey("Given Glance endpoint which does not respond in time", s.T(), func() {
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called with timeout", func() {
+			cfg.AddItem("timeout", ctypes.ConfigValueStr{Value: "50ms"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error names the request which timed out", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Glance API version discovery request timed out")
+			})
+		})
+
+		Convey("When timeout is invalid", func() {
+			cfg.AddItem("timeout", ctypes.ConfigValueStr{Value: "soon"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "Invalid timeout")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsRetry() {
+	Convey("Given Glance endpoint failing with transient error", s.T(), func() {
+		failures := 1
+		target, _ := url.Parse(th.Endpoint())
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures > 0 {
+				failures--
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "collector", "http", "retries"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			retries := openstackintel.GetStats().Retries
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then failed request is retried and counted", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/collector/http/retries")
+				So(mts[1].Data(), ShouldEqual, retries+1)
+			})
+		})
+
+		Convey("When retries are disabled", func() {
+			cfg.AddItem("max_attempts", ctypes.ConfigValueStr{Value: "1"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then collection fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsCache() {
+	Convey("Given image listing cached for an hour", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "1h"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "public", "namespaces"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called twice", func() {
+			collector := New()
+			requests := atomic.LoadInt64(&s.ImageRequests)
+
+			_, err := collector.CollectMetrics([]plugin.MetricType{m1})
+			So(err, ShouldBeNil)
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then images are listed once", func() {
+				So(err, ShouldBeNil)
+				So(atomic.LoadInt64(&s.ImageRequests)-requests, ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+			})
+
+			Convey("and images metrics are tagged with age of listing", func() {
+				So(mts[0].Tags()["data_age"], ShouldEqual, "0")
+				_, found := mts[1].Tags()["data_age"]
+				So(found, ShouldBeFalse)
+			})
+		})
+
+		Convey("When cache is disabled", func() {
+			cfg.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "0"})
+			collector := New()
+			requests := atomic.LoadInt64(&s.ImageRequests)
+
+			collector.CollectMetrics([]plugin.MetricType{m1})
+			collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then images are listed in each collection", func() {
+				So(atomic.LoadInt64(&s.ImageRequests)-requests, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectSelfMetrics() {
+	Convey("Given metric types of collector itself", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "1h"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "unknown", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		self := []plugin.MetricType{}
+		for _, name := range []string{"keystone/logins", "keystone/login_duration", "glance/listings", "glance/images",
+			"cache/hits", "cache/misses", "errors/unauthorized", "http/requests"} {
+			self = append(self, plugin.MetricType{
+				Namespace_: core.NewNamespace(append([]string{"intel", "openstack", "glance", "collector"}, strings.Split(name, "/")...)...),
+				Config_:    cfg.ConfigDataNode})
+		}
+
+		Convey("When tenant metrics are collected twice", func() {
+			collector := New()
+			_, err := collector.CollectMetrics([]plugin.MetricType{m1})
+			So(err, ShouldBeNil)
+			_, err = collector.CollectMetrics([]plugin.MetricType{m2})
+			So(err, ShouldNotBeNil)
+
+			mts, err := collector.CollectMetrics(append([]plugin.MetricType{m1}, self...))
+
+			Convey("Then logins, listings, cache usage and errors are reported", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 9)
+				So(mts[1].Data(), ShouldEqual, 2)
+				So(mts[2].Data(), ShouldBeGreaterThan, 0)
+				So(mts[3].Data(), ShouldEqual, 1)
+				So(mts[4].Data(), ShouldBeGreaterThanOrEqualTo, 2)
+				So(mts[5].Data(), ShouldEqual, 1)
+				So(mts[6].Data(), ShouldEqual, 1)
+				So(mts[7].Data(), ShouldEqual, 1)
+				So(mts[8].Data(), ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectProbeMetrics() {
+	// failures is number of following healthcheck requests which fail with 503
+	failures := int64(0)
+	th.Mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.StoreInt64(&failures, 0)
+		fmt.Fprint(w, "OK")
+	})
+
+	Convey("Given probe metric types of Glance shared by several tenants", s.T(), func() {
+		atomic.StoreInt64(&failures, 0)
+		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("tenant", "name of the tenant").
+				AddStaticElements("images", "public", "count"),
+			Config_: cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "probe", "healthcheck", "status"),
+			Config_:    cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "probe", "images", "latency"),
+			Config_:    cfg.ConfigDataNode}
+		m4 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "probe", "available"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When Glance is healthy", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2, m3, m4})
+
+			Convey("Then each endpoint is probed once and reported as available", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 5)
+				So(mts[2].Data(), ShouldEqual, 200)
+				So(mts[2].Tags()["endpoint"], ShouldEqual, th.Endpoint())
+				So(mts[3].Data(), ShouldBeGreaterThan, 0)
+				So(mts[4].Data(), ShouldEqual, true)
+			})
+		})
+
+		Convey("When Glance healthcheck reports failure once", func() {
+			atomic.StoreInt64(&failures, 1)
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m2, m3, m4})
+
+			Convey("Then endpoint is reported as not available without retrying the probe", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 3)
+				So(mts[0].Data(), ShouldEqual, 503)
+				So(mts[2].Data(), ShouldEqual, false)
+			})
+		})
+	})
+
+	Convey("Given only probe metric types without configured tenant", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "probe", "available"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then single tenant is logged in to probe Glance", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, true)
+				So(collector.stats.Keystone.Logins, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When tenant was already logged in by previous collection", func() {
+			m2 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("tenant", "name of the tenant").
+					AddStaticElements("images", "public", "count"),
+				Config_: cfg.ConfigDataNode}
+			collector := New()
+			_, err := collector.CollectMetrics([]plugin.MetricType{m2})
+			So(err, ShouldBeNil)
+			logins := collector.stats.Keystone.Logins
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then its provider is used without logging in again", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(collector.stats.Keystone.Logins, ShouldEqual, logins)
+			})
+		})
+	})
+
+	Convey("Given Glance which is not available", s.T(), func() {
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/" || r.URL.Path == "/v2/images" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		cfg.AddItem("max_attempts", ctypes.ConfigValueStr{Value: "1"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "probe", "available"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then endpoint is reported as not available instead of failing collection", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/probe/available")
+				So(mts[0].Data(), ShouldEqual, false)
+				So(mts[0].Tags()["endpoint"], ShouldEqual, glance.URL+"/")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectAPIMetrics() {
+	Convey("Given metric types of Glance API versions", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "api", "versions").
+				AddDynamicElement("version", "Glance API version, ex. v2.3").
+				AddStaticElement("status"),
+			Config_: cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "api", "selected"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then status of each available version and selected version are returned", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 7)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/api/versions/v2.3/status")
+				So(mts[0].Namespace().Element(5).Name, ShouldEqual, "version")
+				So(mts[0].Data(), ShouldEqual, "CURRENT")
+				So(mts[0].Tags()["endpoint"], ShouldEqual, th.Endpoint())
+				So(mts[0].Tags()["link"], ShouldNotBeEmpty)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/api/versions/v2.2/status")
+				So(mts[1].Data(), ShouldEqual, "SUPPORTED")
+				So(mts[5].Namespace().String(), ShouldEqual, "/intel/openstack/glance/api/versions/v1.0/status")
+				So(mts[6].Namespace().String(), ShouldEqual, "/intel/openstack/glance/api/selected")
+				So(mts[6].Data(), ShouldEqual, "v2.3")
+				So(mts[6].Tags()["link"], ShouldEqual, mts[0].Tags()["link"])
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsStale() {
+	Convey("Given Glance which removed negotiated API version", s.T(), func() {
+		versionRequests := 0
+		imageFailures := 1
+		target, _ := url.Parse(th.Endpoint())
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/" {
+				versionRequests++
+			}
+			if r.URL.Path == s.Images && imageFailures > 0 {
+				imageFailures--
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then client logs in and negotiates API version again", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(versionRequests, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given Glance which rejects token when Keystone cannot renew it", s.T(), func() {
+		imageFailures := int64(1)
+		target, _ := url.Parse(th.Endpoint())
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == s.Images && atomic.AddInt64(&imageFailures, -1) >= 0 {
+				// re-authentication of the client fails as well, ex. because Keystone is restarted meanwhile
+				atomic.StoreInt64(&s.UnavailableLogins, 1)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then client logs in again and images are listed", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(collector.stats.Keystone.Logins, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given Glance without metadata definitions catalog", s.T(), func() {
+		versionRequests := int64(0)
+		target, _ := url.Parse(th.Endpoint())
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/" {
+				atomic.AddInt64(&versionRequests, 1)
+			}
+			if strings.Contains(r.URL.Path, "/metadefs/") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "public", "namespaces"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then images are collected without logging in and negotiating API version again", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/tenant/images/public/count")
+				So(collector.stats.Keystone.Logins, ShouldEqual, 1)
+				So(atomic.LoadInt64(&versionRequests), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given Glance which fails to serve metadata definitions catalog", s.T(), func() {
+		target, _ := url.Parse(th.Endpoint())
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Path, "/metadefs/") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		cfg.AddItem("max_attempts", ctypes.ConfigValueStr{Value: "1"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "public", "namespaces"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "collection_success"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2, m3})
+
+			Convey("Then images are collected and failure is reported by status", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/tenant/images/public/count")
+				So(mts[1].Data(), ShouldEqual, 0)
+				So(mts[1].Tags()["error"], ShouldStartWith, "Metadata definitions could not be collected")
+			})
+		})
+	})
+
+	Convey("Given credentials changed in configuration", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "1h"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		changed := setupCfg(s.Server.URL, "me", "rotated", "tenant")
+		changed.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "1h"})
+		m2 := plugin.MetricType{
+			Namespace_: m1.Namespace(),
+			Config_:    changed.ConfigDataNode}
+
+		Convey("When tasks with both credentials are collected alternately", func() {
+			collector := New()
+			for _, mts := range [][]plugin.MetricType{{m1}, {m2}, {m1}, {m2}} {
+				_, err := collector.CollectMetrics(mts)
+				So(err, ShouldBeNil)
+			}
+
+			Convey("Then each credentials log in once and do not replace each other", func() {
+				So(collector.stats.Keystone.Logins, ShouldEqual, 2)
+				So(len(collector.providers), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When provider of previous credentials is not used within TTL", func() {
+			collector := New()
+			_, err := collector.CollectMetrics([]plugin.MetricType{m1})
+			So(err, ShouldBeNil)
+			for _, entry := range collector.providers {
+				entry.usedAt = time.Now().Add(-providerTTL - time.Minute)
+			}
+
+			_, err = collector.CollectMetrics([]plugin.MetricType{m2})
+
+			Convey("Then it is evicted together with its image listing", func() {
+				So(err, ShouldBeNil)
+				So(len(collector.providers), ShouldEqual, 1)
+				for auth := range collector.providers {
+					So(auth.Password, ShouldEqual, "rotated")
+				}
+				So(len(collector.listings), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given the same credentials needed by several collections at once", s.T(), func() {
+		opts := openstackintel.AuthOptions{IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", TenantName: "tenant"}
+		collector := New()
+
+		Convey("When they authenticate concurrently", func() {
+			providers := make([]*gophercloud.ProviderClient, 8)
+			errs := make([]error, len(providers))
+			var wg sync.WaitGroup
+			for i := range providers {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					providers[i], errs[i] = collector.authenticate(opts)
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then single login is shared by all of them", func() {
+				for i := range providers {
+					So(errs[i], ShouldBeNil)
+					So(providers[i], ShouldEqual, providers[0])
+				}
+				So(collector.stats.Keystone.Logins, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsConcurrently() {
+	Convey("Given metric types of several tenants", s.T(), func() {
+		tenants := []string{"tenant", "demo", "admin"}
+		requests := [][]plugin.MetricType{}
+		for i, tenant := range tenants {
+			cfg := setupCfg(s.Server.URL, "me", "secret", tenant)
+			// some tenants share cached image listings, others list images in each collection
+			if i%2 == 0 {
+				cfg.AddItem("cache_ttl", ctypes.ConfigValueStr{Value: "1h"})
+			}
+			requests = append(requests, []plugin.MetricType{
+				{
+					Namespace_: core.NewNamespace("intel", "openstack", "glance", tenant, "images", "public", "count"),
+					Config_:    cfg.ConfigDataNode},
+				{
+					Namespace_: core.NewNamespace("intel", "openstack", "glance", tenant, "images", "delta", "created"),
+					Config_:    cfg.ConfigDataNode},
+				{
+					Namespace_: core.NewNamespace("intel", "openstack", "glance", "images", "duplicates", "groups"),
+					Config_:    cfg.ConfigDataNode},
+			})
+		}
+
+		Convey("When CollectMetrics() is called many times at once", func() {
+			collector := New()
+			results := make([][]plugin.MetricType, 10*len(requests))
+			errs := make([]error, len(results))
+
+			var wg sync.WaitGroup
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], errs[i] = collector.CollectMetrics(requests[i%len(requests)])
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then metrics of each tenant are collected", func() {
+				for i, mts := range results {
+					So(errs[i], ShouldBeNil)
+					So(len(mts), ShouldEqual, 3)
+					So(mts[0].Namespace().Strings()[3], ShouldEqual, tenants[i%len(tenants)])
+					So(mts[0].Data(), ShouldEqual, 2)
+					So(mts[1].Data(), ShouldEqual, 0)
+				}
+				So(len(collector.providers), ShouldEqual, len(tenants))
+				So(len(collector.services), ShouldEqual, len(tenants))
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsSharedTenantName() {
+	Convey("Given tenants of the same name in two Keystones", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+		other := setupCfg(strings.Replace(s.Server.URL, "127.0.0.1", "localhost", 1), "me", "secret", "tenant")
+		m2 := plugin.MetricType{
+			Namespace_: m1.Namespace(),
+			Config_:    other.ConfigDataNode}
+		override := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		override.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: th.Endpoint()})
+		m3 := plugin.MetricType{
+			Namespace_: m1.Namespace(),
+			Config_:    override.ConfigDataNode}
+
+		Convey("When tasks collecting them alternate", func() {
+			collector := New()
+			for i := 0; i < 2; i++ {
+				for _, mt := range []plugin.MetricType{m1, m2, m3} {
+					_, err := collector.CollectMetrics([]plugin.MetricType{mt})
+					So(err, ShouldBeNil)
+				}
+			}
+
+			Convey("Then each Keystone keeps its provider and each endpoint its dispatcher", func() {
+				So(collector.stats.Keystone.Logins, ShouldEqual, 2)
+				So(len(collector.providers), ShouldEqual, 2)
+				So(len(collector.services), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsTenants() {
+	Convey("Given metric types of all tenants and no tenant in config", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+		cfg.AddItem("max_concurrency", ctypes.ConfigValueStr{Value: "2"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("tenant", "name of the tenant").
+				AddStaticElements("images", "public", "count"),
+			Config_: cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "unknown", "images", "public", "count"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then metrics of each available tenant are returned in order of tenant names", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/admin/images/public/count")
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/demo/images/public/count")
+				So(mts[1].Data(), ShouldEqual, 2)
+				So(len(collector.providers), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When one of tenants cannot be collected", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then metrics of other tenants are returned", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().Strings()[3], ShouldEqual, "admin")
+				So(mts[1].Namespace().Strings()[3], ShouldEqual, "demo")
+			})
+		})
+
+		Convey("When none of tenants can be collected", func() {
+			_, err := New().CollectMetrics([]plugin.MetricType{m2})
+
+			Convey("Then error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When max_concurrency is invalid", func() {
+			cfg.AddItem("max_concurrency", ctypes.ConfigValueStr{Value: "none"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error describing invalid option is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "max_concurrency")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestTenantsOfBoundCredentials() {
+	Convey("Given application credential bound to single project", s.T(), func() {
+		t := target{auth: openstackintel.AuthOptions{
+			IdentityEndpoint:            s.Server.URL + "/v3/",
+			ApplicationCredentialID:     "423f19a4ac1e4f48bbb4180756e6eb6c",
+			ApplicationCredentialSecret: "rEaqvJka48mpv",
+			TenantName:                  "demo",
+		}}
+		all := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("tenant", "name of the tenant").
+				AddStaticElements("images", "public", "count")}
+		demo := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "demo", "images", "public", "count")}
+		admin := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "admin", "images", "public", "count")}
+
+		Convey("When metrics of all tenants are requested", func() {
+			names, err := tenants(t, []plugin.MetricType{all, demo}, 3)
+
+			Convey("Then only configured tenant is collected", func() {
+				So(err, ShouldBeNil)
+				So(names, ShouldResemble, []string{"demo"})
+			})
+		})
+
+		Convey("When metrics of other tenant are requested", func() {
+			_, err := tenants(t, []plugin.MetricType{all, admin}, 3)
+
+			Convey("Then error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "admin")
+			})
+		})
+
+		Convey("When no tenant is configured", func() {
+			t.auth.TenantName = ""
+			_, err := tenants(t, []plugin.MetricType{all}, 3)
+
+			Convey("Then error asking for tenant is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "set tenant in configuration")
+			})
+		})
+	})
+
+	Convey("Given pre-issued token and trust", s.T(), func() {
+		token := openstackintel.AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "e8ab5a3c4b0a4f1c9f4d", TenantName: "demo"}
+		trust := openstackintel.AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", Username: "me", Password: "secret",
+			DomainName: "Default", TrustID: "9b24ef4c1b3e4b8fa5c1", TenantName: "demo"}
+		rescoped := openstackintel.AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "e8ab5a3c4b0a4f1c9f4d",
+			TenantID: "97ea299c37bb4e04b3779039ea8aba44", TenantName: "demo"}
+		admin := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "admin", "images", "public", "count")}
+
+		Convey("When metrics of other tenant are requested", func() {
+			for _, opts := range []openstackintel.AuthOptions{token, trust, rescoped} {
+				_, err := tenants(target{auth: opts}, []plugin.MetricType{admin}, 3)
+
+				Convey("Then error is returned for "+opts.TokenID+opts.TrustID+opts.TenantID, func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, "bound to project demo")
+				})
+			}
+		})
+
+		Convey("When token is rescoped by project name and domain", func() {
+			token.ProjectDomainName = "Default"
+			names, err := tenants(target{auth: token}, []plugin.MetricType{admin}, 3)
+
+			Convey("Then other tenant is collected", func() {
+				So(err, ShouldBeNil)
+				So(names, ShouldResemble, []string{"admin"})
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsStatus() {
+	Convey("Given status metric types of tenants", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("tenant", "name of the tenant").
+				AddStaticElements("images", "public", "count"),
+			Config_: cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("tenant", "name of the tenant").
+				AddStaticElement("collection_success"),
+			Config_: cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "unknown", "collection_success"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When one of tenants cannot be collected", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2, m3})
+
+			Convey("Then metrics of other tenants are returned and failed tenant is marked", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 5)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/admin/collection_success")
+				So(mts[1].Data(), ShouldEqual, 1)
+				So(mts[3].Namespace().String(), ShouldEqual, "/intel/openstack/glance/demo/collection_success")
+				So(mts[3].Data(), ShouldEqual, 1)
+				So(mts[4].Namespace().String(), ShouldEqual, "/intel/openstack/glance/unknown/collection_success")
+				So(mts[4].Data(), ShouldEqual, 0)
+				So(mts[4].Tags()["error"], ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When the only requested tenant cannot be collected", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m3})
+
+			Convey("Then failure is reported by status metric instead of error", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestForEach() {
+	Convey("Given more tasks than workers", s.T(), func() {
+		var running, maxRunning int64
+		done := make([]bool, 10)
+
+		Convey("When tasks are run in worker pool", func() {
+			forEach(len(done), 3, func(i int) {
+				n := atomic.AddInt64(&running, 1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				done[i] = true
+				atomic.AddInt64(&running, -1)
+			})
+
+			Convey("Then each task is run once and number of workers is not exceeded", func() {
+				for _, d := range done {
+					So(d, ShouldBeTrue)
+				}
+				So(maxRunning, ShouldBeLessThanOrEqualTo, 3)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestGetMetricTypesRegions() {
+	Convey("Given config with multi-region collection enabled", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("regions", ctypes.ConfigValueStr{Value: ""})
+
+		Convey("When GetMetricTypes() is called", func() {
+			mts, err := New().GetMetricTypes(cfg)
+
+			Convey("Then tenant metrics have region element", func() {
+				So(err, ShouldBeNil)
+
+				metricNames := []string{}
+				for _, m := range mts {
+					metricNames = append(metricNames, m.Namespace().String())
+				}
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/tenant/images/public/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/*/tenant/metadefs/public/namespaces"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/glance/images/duplicates/groups"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsRegions() {
+	Convey("Given metric types requested from all regions", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("region", "name of the region").
+				AddStaticElements("tenant", "images", "public", "count"),
+			Config_: cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "images", "duplicates", "groups"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When regions are discovered in service catalog", func() {
+			cfg.AddItem("regions", ctypes.ConfigValueStr{Value: "*"})
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then metrics are collected from each region with Glance endpoint", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionOne/tenant/images/public/count")
+				So(mts[0].Namespace().Element(3).Name, ShouldEqual, "region")
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/images/duplicates/groups")
+			})
+		})
+
+		Convey("When configured region has no Glance endpoint", func() {
+			cfg.AddItem("regions", ctypes.ConfigValueStr{Value: "RegionTwo"})
+
+			_, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then error names the region", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "RegionTwo")
+			})
+		})
+
+		Convey("When one of configured regions has no Glance endpoint", func() {
+			cfg.AddItem("regions", ctypes.ConfigValueStr{Value: "RegionOne, RegionTwo"})
+			m3 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("region", "name of the region").
+					AddStaticElements("tenant", "collection_success"),
+				Config_: cfg.ConfigDataNode}
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1, m3})
+
+			Convey("Then other region is collected and each region is reported by its status", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 3)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionOne/tenant/images/public/count")
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionOne/tenant/collection_success")
+				So(mts[1].Data(), ShouldEqual, 1)
+				So(mts[2].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionTwo/tenant/collection_success")
+				So(mts[2].Data(), ShouldEqual, 0)
+				So(mts[2].Tags()["error"], ShouldContainSubstring, "RegionTwo")
+			})
+		})
+	})
+
+	Convey("Given status of tenant which cannot log in requested from configured regions", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "unknown")
+		cfg.AddItem("regions", ctypes.ConfigValueStr{Value: "RegionOne, RegionTwo"})
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance").
+				AddDynamicElement("region", "name of the region").
+				AddStaticElements("unknown", "collection_success"),
+			Config_: cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.MetricType{m1})
+
+			Convey("Then failure is reported in each configured region", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionOne/unknown/collection_success")
+				So(mts[0].Namespace().Element(3).Name, ShouldEqual, "region")
+				So(mts[0].Data(), ShouldEqual, 0)
+				So(mts[1].Namespace().String(), ShouldEqual, "/intel/openstack/glance/RegionTwo/unknown/collection_success")
+				So(mts[1].Tags()["error"], ShouldNotBeEmpty)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetadefsMetrics() {
+	Convey("Given set of metadefs metric types", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "public", "namespaces"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "public", "properties"),
+			Config_:    cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "metadefs", "private", "namespaces"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2, m3})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and proper metric values and tags are returned", func() {
+				So(len(mts), ShouldEqual, 3)
+
+				metrics := map[string]plugin.MetricType{}
+				for _, m := range mts {
+					metrics[m.Namespace().String()] = m
+				}
+
+				m, ok := metrics["/intel/openstack/glance/tenant/metadefs/public/namespaces"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 2)
+				So(m.Tags()["namespaces"], ShouldEqual, "OS::Compute::Quota,OS::Compute::Watchdog")
+
+				m, ok = metrics["/intel/openstack/glance/tenant/metadefs/public/properties"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 3)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/metadefs/private/namespaces"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 0)
+				So(m.Tags()["namespaces"], ShouldEqual, "")
+			})
+		})
+
+		Convey("When metadefs are collected for several tenants", func() {
+			cfg := setupCfg(s.Server.URL, "me", "secret", "")
+			m4 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("tenant", "name of the tenant").
+					AddStaticElements("metadefs", "public", "namespaces"),
+				Config_: cfg.ConfigDataNode}
+			requests := atomic.LoadInt64(&s.MetadefsRequests)
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m4})
+
+			Convey("Then catalog is read once for all tenants", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Data(), ShouldEqual, 2)
+				So(atomic.LoadInt64(&s.MetadefsRequests)-requests, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given all tenant metrics requested from Glance API v1", s.T(), func() {
+		glance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Content-Type", "application/json")
+			switch strings.Trim(r.URL.Path, "/") {
+			case "":
+				fmt.Fprintf(w, `{"versions": [{"id": "v1.1", "links": [{"href": "http://%s/v1/", "rel": "self"}], "status": "CURRENT"}]}`, r.Host)
+			case "v1/images/detail":
+				fmt.Fprint(w, `{"images": [{"id": "e256d524-bbd7-40af-9bfa-463d86917459", "name": "TestVM", "is_public": true, "size": 13167616, "status": "active", "checksum": "ee1eca47dc88f4879d8a229cc70a07c6"}]}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer glance.Close()
+
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		cfg.AddItem("endpoint_override", ctypes.ConfigValueStr{Value: glance.URL})
+		mts, err := New().GetMetricTypes(cfg)
+		So(err, ShouldBeNil)
+
+		requested := []plugin.MetricType{}
+		for _, mt := range mts {
+			if namespace := mt.Namespace().Strings(); len(namespace) > 4 && (namespace[4] == "images" || namespace[4] == "metadefs" || namespace[4] == statusMetric) {
+				requested = append(requested, plugin.MetricType{Namespace_: mt.Namespace(), Config_: cfg.ConfigDataNode})
+			}
+		}
+
+		Convey("When CollectMetrics() is called", func() {
+			collected, err := New().CollectMetrics(requested)
+
+			Convey("Then images metrics are collected without metadefs metrics", func() {
+				So(err, ShouldBeNil)
+
+				metrics := map[string]plugin.MetricType{}
+				for _, m := range collected {
+					metrics[m.Namespace().String()] = m
+				}
+
+				m, ok := metrics["/intel/openstack/glance/tenant/images/public/count"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 1)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/collection_success"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 1)
+
+				_, ok = metrics["/intel/openstack/glance/tenant/metadefs/public/namespaces"]
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectDuplicatesMetrics() {
+	Convey("Given set of duplicates metric types", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "duplicates", "groups"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "images", "duplicates", "bytes"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and no duplicates are found among images with different checksums", func() {
+				So(len(mts), ShouldEqual, 2)
+				for _, m := range mts {
+					So(m.Data(), ShouldEqual, 0)
+				}
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestFindDuplicates() {
+	Convey("Given list of images with repeated content", s.T(), func() {
+		imgs := []types.Image{
+			{ID: "1", Size: 100, Checksum: "aaa", HashAlgo: "sha512", HashValue: "h1"},
+			{ID: "2", Size: 100, Checksum: "bbb", HashAlgo: "sha512", HashValue: "h1"},
+			{ID: "3", Size: 100, Checksum: "ccc", HashAlgo: "sha512", HashValue: "h1"},
+			{ID: "4", Size: 50, Checksum: "ddd"},
+			{ID: "5", Size: 50, Checksum: "ddd"},
+			{ID: "6", Size: 10, Checksum: "eee"},
+			{ID: "7", Status: "queued"},
+			{ID: "8", Status: "queued"},
+		}
+
+		Convey("When duplicates are searched", func() {
+			dups := findDuplicates(imgs)
+
+			Convey("Then multihash is preferred over checksum and images without data are skipped", func() {
+				So(dups.Groups, ShouldEqual, 2)
+				So(dups.Bytes, ShouldEqual, 250)
+				So(dups.LargestGroup, ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the same images are listed for several tenants", func() {
+			dups := findDuplicates(uniqueImages(imgs, imgs[:2]))
+
+			Convey("Then they are taken into account once", func() {
+				So(dups.Groups, ShouldEqual, 2)
+				So(dups.LargestGroup, ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectDeltaMetrics() {
+	Convey("Given set of images delta metric types", s.T(), func() {
+		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "delta", "created"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "delta", "bytes"),
+			Config_:    cfg.ConfigDataNode}
+
+		Convey("When CollectMetrics() is called twice", func() {
+			collector := New()
+
+			baseline, err1 := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+			mts, err2 := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+
+			Convey("Then no error should be reported", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+			})
+
+			Convey("and first collection creates baseline", func() {
+				So(len(baseline), ShouldEqual, 2)
+				for _, m := range baseline {
+					So(m.Data(), ShouldEqual, 0)
+				}
+			})
+
+			Convey("and unchanged images are not reported", func() {
+				So(len(mts), ShouldEqual, 2)
+				for _, m := range mts {
+					So(m.Data(), ShouldEqual, 0)
+				}
+			})
+		})
+	})
 }
 
-func (s *CollectorSuite) SetupSuite() {
-	// for glance calls
-	th.SetupHTTP()
+func (s *CollectorSuite) TestCompareSnapshot() {
+	Convey("Given images seen during previous collection", s.T(), func() {
+		collector := New()
+		prev := []types.Image{
+			{ID: "1", Name: "cirros", Owner: "admin", Visibility: "private", Status: "active", Size: 100},
+			{ID: "2", Name: "fedora", Owner: "admin", Visibility: "public", Status: "active", Size: 200},
+			{ID: "3", Name: "ubuntu", Owner: "demo", Visibility: "private", Status: "queued", Size: 0},
+		}
+		listedAt := time.Now().Add(-time.Minute)
+		baseline, baselineEvents := collector.advanceSnapshot("task", listing{images: prev, listedAt: listedAt})
 
-	// for identity calls
-	router := mux.NewRouter()
-	s.Server = httptest.NewServer(router)
+		Convey("When images are created, deleted and changed", func() {
+			curr := []types.Image{
+				{ID: "1", Name: "cirros", Owner: "admin", Visibility: "public", Status: "deactivated", Size: 100},
+				{ID: "3", Name: "ubuntu", Owner: "demo", Visibility: "private", Status: "active", Size: 300},
+				{ID: "4", Name: "centos", Owner: "demo", Visibility: "private", Status: "active", Size: 50},
+			}
+			delta, events := collector.advanceSnapshot("task", listing{images: curr, listedAt: listedAt.Add(time.Second)})
 
-	registerIdentityRoot(s, router)
-	registerIdentityTokens(s, router)
-	registerIdentityTenants(s, router, "demo", "admin")
-	registerGlanceApi(s)
-	registerGlanceImages(s, 1000, 2000)
-}
+			Convey("Then baseline reports no changes", func() {
+				So(baseline, ShouldResemble, types.Delta{})
+				So(baselineEvents, ShouldBeEmpty)
+			})
 
-func (s *CollectorSuite) TearDownSuite() {
-	th.TeardownHTTP()
-}
+			Convey("and changes since previous collection are reported", func() {
+				So(delta.Created, ShouldEqual, 1)
+				So(delta.Deleted, ShouldEqual, 1)
+				So(delta.StatusChanged, ShouldEqual, 2)
+				So(delta.Bytes, ShouldEqual, 150)
+			})
 
-func (s *CollectorSuite) TestGetMetricTypesStar() {
-	Convey("Given config with enpoint, user and password defined", s.T(), func() {
-		cfg := setupCfg(s.Server.URL, "me", "secret", "")
+			Convey("and event is reported for each change", func() {
+				So(len(events), ShouldEqual, 5)
+				So(events[0].Type, ShouldEqual, "status_changed")
+				So(events[0].Tags(), ShouldResemble, map[string]string{
+					"image_id":   "1",
+					"image_name": "cirros",
+					"owner":      "admin",
+					"old_value":  "active",
+					"new_value":  "deactivated",
+				})
+				So(events[1].Type, ShouldEqual, "visibility_changed")
+				So(events[1].Old, ShouldEqual, "private")
+				So(events[1].New, ShouldEqual, "public")
+				So(events[2].Type, ShouldEqual, "deleted")
+				So(events[2].Image.Name, ShouldEqual, "fedora")
+				So(events[3].Type, ShouldEqual, "status_changed")
+				So(events[4].Type, ShouldEqual, "created")
+				So(events[4].Image.ID, ShouldEqual, "4")
+			})
 
-		Convey("When GetMetricTypes() is called", func() {
-			collector := New()
-			mts, err := collector.GetMetricTypes(cfg)
+			Convey("and events metrics are filtered by requested event type", func() {
+				requested := plugin.MetricType{
+					Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "events").AddDynamicElement("event_type", "type of image event"),
+				}
+				So(len(eventMetrics(requested, events)), ShouldEqual, 5)
 
-			Convey("Then no error should be reported", func() {
-				So(err, ShouldBeNil)
+				requested.Namespace_[5].Value = "status_changed"
+				mts := eventMetrics(requested, events)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Namespace().String(), ShouldEqual, "/intel/openstack/glance/tenant/events/status_changed")
+				So(mts[0].Tags()["image_id"], ShouldEqual, "1")
 			})
 
-			Convey("and proper metric types are returned", func() {
-				metricNames := []string{}
-				for _, m := range mts {
-					metricNames = append(metricNames, m.Namespace().String())
-				}
+			Convey("and changes are reported only once", func() {
+				delta, events := collector.advanceSnapshot("task", listing{images: curr, listedAt: listedAt.Add(2 * time.Second)})
+				So(delta.Created, ShouldEqual, 0)
+				So(events, ShouldBeEmpty)
+			})
 
-				So(len(mts), ShouldEqual, 6)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/private/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/public/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/shared/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/private/bytes"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/public/bytes"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/*/images/shared/bytes"), ShouldBeTrue)
+			Convey("and listing older than snapshot does not replace it", func() {
+				delta, events := collector.advanceSnapshot("task", listing{images: prev, listedAt: listedAt})
+				So(delta, ShouldResemble, types.Delta{})
+				So(events, ShouldBeEmpty)
+
+				delta, events = collector.advanceSnapshot("task", listing{images: curr, listedAt: listedAt.Add(2 * time.Second)})
+				So(delta.Created, ShouldEqual, 0)
+				So(events, ShouldBeEmpty)
+			})
+
+			Convey("and snapshots of other tasks are not affected", func() {
+				delta, events := collector.advanceSnapshot("other", listing{images: curr, listedAt: listedAt})
+				So(delta, ShouldResemble, types.Delta{})
+				So(events, ShouldBeEmpty)
+			})
+
+			Convey("and snapshots not stored within TTL are evicted", func() {
+				collector.snapshots["removed"] = storedSnapshot{images: newSnapshot(prev), storedAt: time.Now().Add(-snapshotTTL - time.Minute)}
+
+				collector.advanceSnapshot("task", listing{images: curr, listedAt: time.Now()})
+				_, found := collector.snapshots["removed"]
+				So(found, ShouldBeFalse)
+				So(len(collector.snapshots), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When overlapping collections advance snapshot with the same listing", func() {
+			curr := listing{images: prev[:2], listedAt: listedAt.Add(time.Second)}
+			events := make([][]imageEvent, 8)
+			var wg sync.WaitGroup
+			for i := range events {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, events[i] = collector.advanceSnapshot("task", curr)
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then changes are reported by single collection", func() {
+				reported := 0
+				for i := range events {
+					reported += len(events[i])
+				}
+				So(reported, ShouldEqual, 1)
 			})
 		})
 	})
 }
 
-func (s *CollectorSuite) TestGetMetricTypesTenant() {
-	Convey("Given config with enpoint, user and password defined", s.T(), func() {
+func (s *CollectorSuite) TestCollectServersUsageMetrics() {
+	Convey("Given set of servers usage metric types", s.T(), func() {
 		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
+		m1 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "unused", "count"),
+			Config_:    cfg.ConfigDataNode}
+		m2 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "unused", "bytes"),
+			Config_:    cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "image").
+				AddDynamicElement("image_id", "ID of the image").
+				AddStaticElement("in_use_by_servers"),
+			Config_: cfg.ConfigDataNode}
 
-		Convey("When GetMetricTypes() is called", func() {
+		Convey("When CollectMetrics() is called", func() {
 			collector := New()
-			mts, err := collector.GetMetricTypes(cfg)
+
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2, m3})
 
 			Convey("Then no error should be reported", func() {
 				So(err, ShouldBeNil)
 			})
 
-			Convey("and proper metric types are returned", func() {
-				metricNames := []string{}
+			Convey("and images not used by servers are reported", func() {
+				So(len(mts), ShouldEqual, 4)
+
+				metrics := map[string]plugin.MetricType{}
 				for _, m := range mts {
-					metricNames = append(metricNames, m.Namespace().String())
+					metrics[m.Namespace().String()] = m
 				}
 
-				So(len(mts), ShouldEqual, 6)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/private/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/public/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/shared/count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/private/bytes"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/public/bytes"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/glance/tenant/images/shared/bytes"), ShouldBeTrue)
+				m, ok := metrics["/intel/openstack/glance/tenant/images/unused/count"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 1)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/images/unused/bytes"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, s.Img2Size)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/image/5ead7530-3293-40d2-a0ca-f441a33a99e4/in_use_by_servers"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 2)
+				So(m.Tags()["image_name"], ShouldEqual, "cirros-0.3.4-x86_64-uec")
+
+				m, ok = metrics["/intel/openstack/glance/tenant/image/e0f483ec-713f-4768-ba1a-220a16b97287/in_use_by_servers"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When servers usage is collected for several tenants", func() {
+			cfg := setupCfg(s.Server.URL, "me", "secret", "")
+			m4 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("tenant", "name of the tenant").
+					AddStaticElements("images", "unused", "count"),
+				Config_: cfg.ConfigDataNode}
+			requests := atomic.LoadInt64(&s.ServerRequests)
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m4})
+
+			Convey("Then servers are listed once for all tenants", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Data(), ShouldEqual, 1)
+				So(mts[1].Data(), ShouldEqual, 1)
+				So(atomic.LoadInt64(&s.ServerRequests)-requests, ShouldEqual, 1)
 			})
 		})
 	})
 }
 
-func (s *CollectorSuite) TestCollectMetrics() {
-	Convey("Given set of metric types", s.T(), func() {
+func (s *CollectorSuite) TestCollectVolumesUsageMetrics() {
+	Convey("Given set of volumes usage metric types", s.T(), func() {
 		cfg := setupCfg(s.Server.URL, "me", "secret", "tenant")
 		m1 := plugin.MetricType{
-			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "count"),
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "in_use_by_volumes", "count"),
 			Config_:    cfg.ConfigDataNode}
 		m2 := plugin.MetricType{
-			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "public", "bytes"),
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "in_use_by_volumes", "bytes"),
+			Config_:    cfg.ConfigDataNode}
+		m3 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "images", "in_use_by_volumes", "pending_delete"),
+			Config_:    cfg.ConfigDataNode}
+		m4 := plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "openstack", "glance", "tenant", "image", "e0f483ec-713f-4768-ba1a-220a16b97287", "in_use_by_volumes"),
 			Config_:    cfg.ConfigDataNode}
 
-		Convey("When ColelctMetrics() is called", func() {
+		Convey("When CollectMetrics() is called", func() {
 			collector := New()
 
-			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2, m3, m4})
 
 			Convey("Then no error should be reported", func() {
 				So(err, ShouldBeNil)
 			})
 
-			Convey("and proper metric types are returned", func() {
-				metricNames := map[string]interface{}{}
+			Convey("and images backing volumes are reported", func() {
+				So(len(mts), ShouldEqual, 4)
+
+				metrics := map[string]plugin.MetricType{}
 				for _, m := range mts {
-					ns := m.Namespace().String()
-					metricNames[ns] = m.Data()
+					metrics[m.Namespace().String()] = m
 				}
-				fmt.Println(metricNames)
-				So(len(mts), ShouldEqual, 2)
 
-				val, ok := metricNames["/intel/openstack/glance/tenant/images/public/count"]
+				m, ok := metrics["/intel/openstack/glance/tenant/images/in_use_by_volumes/count"]
 				So(ok, ShouldBeTrue)
-				So(val, ShouldEqual, 2)
+				So(m.Data(), ShouldEqual, 1)
 
-				val, ok = metricNames["/intel/openstack/glance/tenant/images/public/bytes"]
+				m, ok = metrics["/intel/openstack/glance/tenant/images/in_use_by_volumes/bytes"]
 				So(ok, ShouldBeTrue)
-				So(val, ShouldEqual, s.Img1Size+s.Img2Size)
+				So(m.Data(), ShouldEqual, s.Img2Size)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/images/in_use_by_volumes/pending_delete"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 0)
+
+				m, ok = metrics["/intel/openstack/glance/tenant/image/e0f483ec-713f-4768-ba1a-220a16b97287/in_use_by_volumes"]
+				So(ok, ShouldBeTrue)
+				So(m.Data(), ShouldEqual, 2)
+				So(m.Tags()["image_name"], ShouldEqual, "cirros-0.3.4-x86_64-uec-kernel")
+			})
+		})
+
+		Convey("When volumes usage is collected for several tenants", func() {
+			cfg := setupCfg(s.Server.URL, "me", "secret", "")
+			m5 := plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "openstack", "glance").
+					AddDynamicElement("tenant", "name of the tenant").
+					AddStaticElements("images", "in_use_by_volumes", "count"),
+				Config_: cfg.ConfigDataNode}
+			requests := atomic.LoadInt64(&s.VolumeRequests)
+
+			mts, err := New().CollectMetrics([]plugin.MetricType{m5})
+
+			Convey("Then volumes are listed once for all tenants", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 2)
+				So(mts[0].Data(), ShouldEqual, 1)
+				So(mts[1].Data(), ShouldEqual, 1)
+				So(atomic.LoadInt64(&s.VolumeRequests)-requests, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestVolumeImages() {
+	Convey("Given images and number of volumes created from them", s.T(), func() {
+		imgs := []types.Image{
+			{ID: "1", Size: 100, Status: "active"},
+			{ID: "2", Size: 200, Status: "pending_delete"},
+			{ID: "3", Size: 300, Status: "active"},
+		}
+		usage := map[string]int{"1": 3, "2": 1, "unknown": 2}
+
+		Convey("When volumeImages() is called", func() {
+			used := volumeImages(imgs, usage)
+
+			Convey("Then only images referenced by volumes are counted", func() {
+				So(used.Count, ShouldEqual, 2)
+				So(used.Bytes, ShouldEqual, 300)
+			})
+
+			Convey("and images marked for deletion are reported", func() {
+				So(used.PendingDelete, ShouldEqual, 1)
 			})
 		})
 	})
@@ -210,6 +2057,17 @@ func registerIdentityTokens(s *CollectorSuite, r *mux.Router) {
 	s.V2 = "v2"
 	s.Token = "2ed210f132564f21b178afb197ee99e3"
 	r.HandleFunc("/v2.0/tokens", func(w http.ResponseWriter, r *http.Request) {
+		// user has no role in project named unknown
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), `"tenantName":"unknown"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if atomic.AddInt64(&s.UnavailableLogins, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.StoreInt64(&s.UnavailableLogins, 0)
 		fmt.Fprintf(w, `
 				{
 					"access": {
@@ -233,6 +2091,34 @@ func registerIdentityTokens(s *CollectorSuite, r *mux.Router) {
 								"endpoints_links": [],
 								"name": "glance",
 								"type": "image"
+							},
+							{
+								"endpoints": [
+									{
+										"adminURL": "%s",
+										"id": "0ae4f5a7e4e04bbd9c0f2dcbd4f1c9a2",
+										"internalURL": "%s",
+										"publicURL": "%s",
+										"region": "RegionOne"
+									}
+								],
+								"endpoints_links": [],
+								"name": "nova",
+								"type": "compute"
+							},
+							{
+								"endpoints": [
+									{
+										"adminURL": "%s",
+										"id": "5b1d5c0e7c5c4d0a9a0a8f2c6a3f1e77",
+										"internalURL": "%s",
+										"publicURL": "%s",
+										"region": "RegionOne"
+									}
+								],
+								"endpoints_links": [],
+								"name": "cinderv3",
+								"type": "volumev3"
 							}
 						],
 						"token": {
@@ -252,10 +2138,62 @@ func registerIdentityTokens(s *CollectorSuite, r *mux.Router) {
 			th.Endpoint(),
 			th.Endpoint(),
 			th.Endpoint(),
+			th.Endpoint()+"compute/v2.1/",
+			th.Endpoint()+"compute/v2.1/",
+			th.Endpoint()+"compute/v2.1/",
+			s.BlockStorage.URL+"/v3/97ea299c37bb4e04b3779039ea8aba44",
+			s.BlockStorage.URL+"/v3/97ea299c37bb4e04b3779039ea8aba44",
+			s.BlockStorage.URL+"/v3/97ea299c37bb4e04b3779039ea8aba44",
 			s.Token)
 	})
 }
 
+func registerIdentityCatalog(s *CollectorSuite, r *mux.Router) {
+	r.HandleFunc("/v3/auth/catalog", func(w http.ResponseWriter, r *http.Request) {
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprintf(w, `
+			{
+				"catalog": [
+					{
+						"endpoints": [
+							{
+								"id": "3ffe125aa59547029ed774c10b932349",
+								"interface": "public",
+								"region": "RegionOne",
+								"url": "%s"
+							},
+							{
+								"id": "a5f1e0c2b3d44c8e9f7a6b5c4d3e2f10",
+								"interface": "internal",
+								"region": "RegionThree",
+								"url": "%s"
+							}
+						],
+						"id": "5a3b7c9d1e2f4a6b8c0d2e4f6a8b0c1d",
+						"name": "glance",
+						"type": "image"
+					},
+					{
+						"endpoints": [
+							{
+								"id": "0ae4f5a7e4e04bbd9c0f2dcbd4f1c9a2",
+								"interface": "public",
+								"region": "RegionTwo",
+								"url": "%scompute/v2.1/"
+							}
+						],
+						"id": "6b4c8d0e2f3a5b7c9d1e3f5a7b9c1d2e",
+						"name": "nova",
+						"type": "compute"
+					}
+				]
+			}
+		`, th.Endpoint(), th.Endpoint(), th.Endpoint())
+	})
+}
+
 func registerIdentityTenants(s *CollectorSuite, r *mux.Router, tenant1 string, tenant2 string) {
 	s.Tenant1 = tenant1
 	s.Tenant2 = tenant2
@@ -376,6 +2314,7 @@ func registerGlanceImages(s *CollectorSuite, size1 int, size2 int) {
 
 	th.Mux.HandleFunc(s.Images, func(w http.ResponseWriter, r *http.Request) {
 		th.TestMethod(s.T(), r, "GET")
+		atomic.AddInt64(&s.ImageRequests, 1)
 		//th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
 
 		w.Header().Add("Content-Type", "application/json")
@@ -435,3 +2374,181 @@ func registerGlanceImages(s *CollectorSuite, size1 int, size2 int) {
 	})
 
 }
+
+func registerGlanceMetadefs(s *CollectorSuite) {
+	namespaces := "/" + s.V2 + "/metadefs/namespaces"
+
+	th.Mux.HandleFunc(namespaces, func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		atomic.AddInt64(&s.MetadefsRequests, 1)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"first": "/v2/metadefs/namespaces",
+				"namespaces": [
+					{
+						"namespace": "OS::Compute::Quota",
+						"resource_type_associations": [
+							{ "name": "OS::Nova::Flavor" }
+						],
+						"visibility": "public"
+					},
+					{
+						"namespace": "OS::Compute::Watchdog",
+						"resource_type_associations": [
+							{ "name": "OS::Glance::Image" }
+						],
+						"visibility": "public"
+					}
+				],
+				"schema": "/v2/schemas/metadefs/namespaces"
+			}
+		`)
+	})
+
+	th.Mux.HandleFunc(namespaces+"/OS::Compute::Quota", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"namespace": "OS::Compute::Quota",
+				"properties": {
+					"quota:cpu_period": { "type": "integer" }
+				},
+				"visibility": "public"
+			}
+		`)
+	})
+
+	th.Mux.HandleFunc(namespaces+"/OS::Compute::Watchdog", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"namespace": "OS::Compute::Watchdog",
+				"properties": {
+					"hw_watchdog_action": { "type": "string" },
+					"hw_watchdog_timeout": { "type": "integer" }
+				},
+				"visibility": "public"
+			}
+		`)
+	})
+}
+
+func registerComputeServers(s *CollectorSuite) {
+	th.Mux.HandleFunc("/compute/v2.1/servers/detail", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestFormValues(s.T(), r, map[string]string{"all_tenants": "1"})
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		// servers are split into two pages to verify that next links are followed
+		if r.URL.Query().Get("marker") == "" {
+			atomic.AddInt64(&s.ServerRequests, 1)
+			fmt.Fprintf(w, `
+				{
+					"servers": [
+						{
+							"id": "9e5476bd-a4ec-4653-93d6-72c93aa682ba",
+							"name": "vm1",
+							"status": "ACTIVE",
+							"tenant_id": "97ea299c37bb4e04b3779039ea8aba44",
+							"image": {
+								"id": "5ead7530-3293-40d2-a0ca-f441a33a99e4",
+								"links": []
+							}
+						},
+						{
+							"id": "e3b2c7e1-0a0e-4d4f-9d4c-8a6f1c1e2b3a",
+							"name": "vm2",
+							"status": "ACTIVE",
+							"tenant_id": "97ea299c37bb4e04b3779039ea8aba44",
+							"image": ""
+						}
+					],
+					"servers_links": [
+						{
+							"href": "%scompute/v2.1/servers/detail?all_tenants=1&marker=e3b2c7e1-0a0e-4d4f-9d4c-8a6f1c1e2b3a",
+							"rel": "next"
+						}
+					]
+				}
+			`, th.Endpoint())
+			return
+		}
+
+		fmt.Fprintf(w, `
+			{
+				"servers": [
+					{
+						"id": "a1d2a0f4-67ad-4c6c-8d41-7e1f6f0c8f3d",
+						"name": "vm3",
+						"status": "SHUTOFF",
+						"tenant_id": "45asdas32",
+						"image": {
+							"id": "5ead7530-3293-40d2-a0ca-f441a33a99e4",
+							"links": []
+						}
+					}
+				]
+			}
+		`)
+	})
+}
+
+func registerBlockStorageVolumes(s *CollectorSuite) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v3/{project_id}/volumes/detail", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestFormValues(s.T(), r, map[string]string{"all_tenants": "1"})
+		atomic.AddInt64(&s.VolumeRequests, 1)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, `
+			{
+				"volumes": [
+					{
+						"id": "6edbc2f4-1507-44f8-ac0d-eed1d2608d38",
+						"name": "vol1",
+						"status": "in-use",
+						"size": 1,
+						"os-vol-tenant-attr:tenant_id": "97ea299c37bb4e04b3779039ea8aba44",
+						"volume_image_metadata": {
+							"image_id": "e0f483ec-713f-4768-ba1a-220a16b97287",
+							"image_name": "cirros-0.3.4-x86_64-uec-kernel"
+						}
+					},
+					{
+						"id": "2b955850-f177-45f7-9f49-ecb2c256d161",
+						"name": "vol2",
+						"status": "available",
+						"size": 2,
+						"os-vol-tenant-attr:tenant_id": "45asdas32",
+						"volume_image_metadata": {
+							"image_id": "e0f483ec-713f-4768-ba1a-220a16b97287",
+							"image_name": "cirros-0.3.4-x86_64-uec-kernel"
+						}
+					},
+					{
+						"id": "b0b2a2b4-bc6c-4d0f-93d4-0b9a2b9e4ad4",
+						"name": "empty",
+						"status": "available",
+						"size": 10,
+						"os-vol-tenant-attr:tenant_id": "97ea299c37bb4e04b3779039ea8aba44"
+					}
+				]
+			}
+		`)
+	})
+	return router
+}
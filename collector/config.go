@@ -0,0 +1,333 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+
+	"github.com/intelsdi-x/snap-plugin-utilities/config"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
+)
+
+// configDefaults lists options read from configuration with their default values. Options are strings,
+// numbers, durations and booleans are parsed when collection starts. Empty string means option is not set.
+var configDefaults = []struct {
+	name  string
+	value string
+}{
+	// Keystone endpoint and credentials
+	{"endpoint", ""},
+	{"user", ""},
+	{"password", ""},
+	{"project_id", ""},
+	{"domain_name", ""},
+	{"domain_id", ""},
+	{"user_domain_name", ""},
+	{"user_domain_id", ""},
+	{"project_domain_name", ""},
+	{"project_domain_id", ""},
+	{"application_credential_id", ""},
+	{"application_credential_name", ""},
+	{"application_credential_secret", ""},
+	{"token", ""},
+	{"trust_id", ""},
+	{"cloud", ""},
+	{"clouds", ""},
+	{"clouds_file", ""},
+
+	// TLS, timeouts, proxy and retries
+	{"ca_file", ""},
+	{"cert_file", ""},
+	{"key_file", ""},
+	{"insecure", ""},
+	{"timeout", "0"},
+	{"connect_timeout", "0"},
+	{"proxy_url", ""},
+	{"max_attempts", strconv.Itoa(openstackintel.DefaultMaxAttempts)},
+
+	// endpoint selection, caching and concurrency
+	{"region", ""},
+	{"interface", "public"},
+	{"endpoint_override", ""},
+	{"cache_ttl", "0"},
+	{"max_concurrency", "1"},
+}
+
+// configPolicy declares string rules of options read from configuration, so Snap rejects invalid task before it starts.
+// Options "tenant" and "regions" have no default value, because they change namespace of metrics when they are set.
+func configPolicy() (*cpolicy.ConfigPolicyNode, error) {
+	node := cpolicy.NewPolicyNode()
+
+	for _, option := range configDefaults {
+		rule, err := cpolicy.NewStringRule(option.name, false, option.value)
+		if err != nil {
+			return nil, err
+		}
+		node.Add(rule)
+	}
+
+	for _, name := range []string{"tenant", "regions"} {
+		rule, err := cpolicy.NewStringRule(name, false)
+		if err != nil {
+			return nil, err
+		}
+		node.Add(rule)
+	}
+
+	return node, nil
+}
+
+// authOptions reads Keystone endpoint and credentials from configuration and validates them.
+// Values missing in configuration are taken from cloud entry of clouds.yaml, or from OS_* environment variables
+// if no cloud is selected, which are consulted only when cloud is requested or endpoint is not configured.
+// Environment is not merged into cloud entry, so auth methods of both are never mixed, as in OpenStack clients.
+func authOptions(cfg interface{}) (openstackintel.AuthOptions, error) {
+	transport, err := transportOptions(cfg)
+	if err != nil {
+		return openstackintel.AuthOptions{}, err
+	}
+
+	opts := openstackintel.AuthOptions{
+		IdentityEndpoint:            configString(cfg, "endpoint"),
+		TenantName:                  configString(cfg, "tenant"),
+		Username:                    configString(cfg, "user"),
+		Password:                    configString(cfg, "password"),
+		TenantID:                    configString(cfg, "project_id"),
+		DomainName:                  configString(cfg, "domain_name"),
+		DomainID:                    configString(cfg, "domain_id"),
+		UserDomainName:              configString(cfg, "user_domain_name"),
+		UserDomainID:                configString(cfg, "user_domain_id"),
+		ProjectDomainName:           configString(cfg, "project_domain_name"),
+		ProjectDomainID:             configString(cfg, "project_domain_id"),
+		ApplicationCredentialID:     configString(cfg, "application_credential_id"),
+		ApplicationCredentialName:   configString(cfg, "application_credential_name"),
+		ApplicationCredentialSecret: configString(cfg, "application_credential_secret"),
+		TokenID:                     configString(cfg, "token"),
+		TrustID:                     configString(cfg, "trust_id"),
+		Transport:                   transport,
+	}
+
+	cloud := configString(cfg, "cloud")
+	if cloud != "" || opts.IdentityEndpoint == "" {
+		if cloud == "" {
+			cloud = os.Getenv("OS_CLOUD")
+		}
+		if cloud != "" {
+			cloudOpts, err := openstackintel.LoadCloud(cloud, configString(cfg, "clouds_file"))
+			if err != nil {
+				return opts, err
+			}
+			opts = opts.WithDefaults(cloudOpts)
+		} else {
+			opts = opts.WithDefaults(openstackintel.AuthOptionsFromEnv())
+		}
+	}
+
+	// insecure set in configuration overrides verify of cloud entry and OS_INSECURE, also when it is false
+	if configString(cfg, "insecure") != "" {
+		opts.Transport.Insecure = configBool(cfg, "insecure")
+	}
+
+	return withTenant(opts)
+}
+
+// cloudAuthOptions reads Keystone endpoint and credentials of given cloud from clouds.yaml and validates them.
+// Credentials configured for task are not used, only tenant and TLS options are taken from configuration if cloud has none.
+func cloudAuthOptions(cfg interface{}, cloud string) (openstackintel.AuthOptions, error) {
+	transport, err := transportOptions(cfg)
+	if err != nil {
+		return openstackintel.AuthOptions{}, err
+	}
+
+	opts, err := openstackintel.LoadCloud(cloud, configString(cfg, "clouds_file"))
+	if err != nil {
+		return opts, err
+	}
+	// TLS options of the cloud entry take precedence over options configured for task
+	opts = opts.WithDefaults(openstackintel.AuthOptions{Transport: transport})
+
+	if opts.TenantName == "" && opts.TenantID == "" {
+		opts.TenantName = configString(cfg, "tenant")
+	}
+
+	return withTenant(opts)
+}
+
+// withTenant sets tenant name used in metrics namespace and validates auth options
+func withTenant(opts openstackintel.AuthOptions) (openstackintel.AuthOptions, error) {
+	// project ID names metrics if tenant name is not known, if neither is set tenants are selected by namespace
+	if opts.TenantName == "" {
+		opts.TenantName = opts.TenantID
+	}
+
+	return opts, opts.Validate()
+}
+
+// transportOptions reads TLS, timeout and proxy options of HTTP client used for Keystone and services
+func transportOptions(cfg interface{}) (openstackintel.TransportOptions, error) {
+	opts := openstackintel.TransportOptions{
+		CAFile:   configString(cfg, "ca_file"),
+		CertFile: configString(cfg, "cert_file"),
+		KeyFile:  configString(cfg, "key_file"),
+		Insecure: configBool(cfg, "insecure"),
+		ProxyURL: configString(cfg, "proxy_url"),
+	}
+
+	var err error
+	if opts.Timeout, err = configDuration(cfg, "timeout"); err != nil {
+		return opts, err
+	}
+	if opts.ConnectTimeout, err = configDuration(cfg, "connect_timeout"); err != nil {
+		return opts, err
+	}
+	if opts.MaxAttempts, err = configInt(cfg, "max_attempts"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// endpointOpts reads region, interface and endpoint override used to select Glance endpoint
+func endpointOpts(cfg interface{}) (openstackintel.EndpointOpts, error) {
+	eo := openstackintel.EndpointOpts{Override: configString(cfg, "endpoint_override")}
+	eo.Region = configString(cfg, "region")
+
+	switch iface := strings.TrimSuffix(configString(cfg, "interface"), "URL"); iface {
+	case "", "public":
+		eo.Availability = gophercloud.AvailabilityPublic
+	case "internal":
+		eo.Availability = gophercloud.AvailabilityInternal
+	case "admin":
+		eo.Availability = gophercloud.AvailabilityAdmin
+	default:
+		return eo, fmt.Errorf("Invalid interface %s, expected one of public, internal, admin", iface)
+	}
+
+	return eo, nil
+}
+
+// configRegions returns list of regions to collect metrics from and true if multi-region collection is configured
+// Empty list means that regions have to be discovered in service catalog.
+func configRegions(cfg interface{}) ([]string, bool) {
+	item, err := config.GetConfigItem(cfg, "regions")
+	if err != nil {
+		return nil, false
+	}
+
+	value, _ := item.(string)
+	regions := []string{}
+	for _, region := range strings.Split(value, ",") {
+		if region = strings.TrimSpace(region); region != "" && region != "*" {
+			regions = append(regions, region)
+		}
+	}
+
+	return regions, true
+}
+
+// configClouds returns names of clouds from clouds.yaml to collect metrics from, or nil if single cloud is configured
+func configClouds(cfg interface{}) []string {
+	var clouds []string
+	for _, cloud := range strings.Split(configString(cfg, "clouds"), ",") {
+		if cloud = strings.TrimSpace(cloud); cloud != "" {
+			clouds = append(clouds, cloud)
+		}
+	}
+	return clouds
+}
+
+// configBool returns value of optional boolean config item, given as bool or string, or false if it is not set
+func configBool(cfg interface{}, name string) bool {
+	item, err := config.GetConfigItem(cfg, name)
+	if err != nil {
+		return false
+	}
+
+	switch value := item.(type) {
+	case bool:
+		return value
+	case string:
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	return false
+}
+
+// configInt returns value of optional positive integer config item, given as number or string, or zero if it is not set
+func configInt(cfg interface{}, name string) (int, error) {
+	item, err := config.GetConfigItem(cfg, name)
+	if err != nil {
+		return 0, nil
+	}
+
+	var value int
+	switch item := item.(type) {
+	case int:
+		value = item
+	case string:
+		if value, err = strconv.Atoi(item); err != nil {
+			return 0, fmt.Errorf("Invalid %s %s, expected positive number", name, item)
+		}
+	default:
+		return 0, fmt.Errorf("Invalid %s %v, expected positive number", name, item)
+	}
+	if value < 1 {
+		return 0, fmt.Errorf("Invalid %s %d, expected positive number", name, value)
+	}
+
+	return value, nil
+}
+
+// configDuration returns value of optional duration config item, given as duration (ex. 30s) or number of seconds,
+// or zero if it is not set
+func configDuration(cfg interface{}, name string) (time.Duration, error) {
+	value := configString(cfg, name)
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("Invalid %s %s, expected duration, ex. 30s", name, value)
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("Invalid %s %s, expected positive duration", name, value)
+	}
+
+	return duration, nil
+}
+
+// configString returns value of optional string config item or empty string if it is not set
+func configString(cfg interface{}, name string) string {
+	item, err := config.GetConfigItem(cfg, name)
+	if err != nil {
+		return ""
+	}
+	value, _ := item.(string)
+	return value
+}
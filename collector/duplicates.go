@@ -0,0 +1,79 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// contentHash returns key identifying image content. Multihash (os_hash_algo, os_hash_value)
+// is preferred when present, otherwise MD5 checksum is used. Images without data
+// (ex. in queued state) have no hash and cannot be compared
+func contentHash(img types.Image) string {
+	if img.HashAlgo != "" && img.HashValue != "" {
+		return img.HashAlgo + ":" + img.HashValue
+	}
+	if img.Checksum != "" {
+		return "md5:" + img.Checksum
+	}
+	return ""
+}
+
+// findDuplicates groups images with the same content and calculates number of groups,
+// bytes which could be reclaimed by keeping single image in each group and size of the largest group
+func findDuplicates(imgs []types.Image) types.Duplicates {
+	groups := map[string][]types.Image{}
+	for _, img := range imgs {
+		hash := contentHash(img)
+		if hash == "" {
+			continue
+		}
+		groups[hash] = append(groups[hash], img)
+	}
+
+	dups := types.Duplicates{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		dups.Groups += 1
+		for _, img := range group[1:] {
+			dups.Bytes += img.Size
+		}
+		if len(group) > dups.LargestGroup {
+			dups.LargestGroup = len(group)
+		}
+	}
+
+	return dups
+}
+
+// uniqueImages merges lists of images collected for different tenants,
+// images visible for more than one tenant (ex. public) are taken into account once
+func uniqueImages(lists ...[]types.Image) []types.Image {
+	seen := map[string]bool{}
+	imgs := []types.Image{}
+	for _, list := range lists {
+		for _, img := range list {
+			if seen[img.ID] {
+				continue
+			}
+			seen[img.ID] = true
+			imgs = append(imgs, img)
+		}
+	}
+	return imgs
+}
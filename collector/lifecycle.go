@@ -0,0 +1,220 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// snapshotTTL is time after which snapshot which was not stored again is evicted, ex. snapshot of removed
+// task or tenant, or of task which configuration changed
+const snapshotTTL = 24 * time.Hour
+
+// snapshot holds images seen during previous collection indexed by image ID
+type snapshot map[string]types.Image
+
+// storedSnapshot is snapshot of the task together with time the images were listed at and time it was stored at
+type storedSnapshot struct {
+	images   snapshot
+	listedAt time.Time
+	storedAt time.Time
+}
+
+// newSnapshot creates snapshot from list of images
+func newSnapshot(imgs []types.Image) snapshot {
+	snap := snapshot{}
+	for _, img := range imgs {
+		snap[img.ID] = img
+	}
+	return snap
+}
+
+// imageEvent represents change of single image observed between collections
+type imageEvent struct {
+	Type  string
+	Image types.Image
+	Old   string
+	New   string
+}
+
+// Tags returns event details in form of metric tags
+func (e imageEvent) Tags() map[string]string {
+	return map[string]string{
+		"image_id":   e.Image.ID,
+		"image_name": e.Image.Name,
+		"owner":      e.Image.Owner,
+		"old_value":  e.Old,
+		"new_value":  e.New,
+	}
+}
+
+// imageEvents lists images which were created, deleted or changed visibility or status
+// since previous snapshot. Events are sorted by image ID to keep stable order.
+func imageEvents(prev snapshot, curr []types.Image) []imageEvent {
+	events := []imageEvent{}
+	seen := map[string]bool{}
+
+	for _, img := range curr {
+		seen[img.ID] = true
+
+		old, found := prev[img.ID]
+		if !found {
+			events = append(events, imageEvent{Type: "created", Image: img, New: img.Status})
+			continue
+		}
+		if old.Visibility != img.Visibility {
+			events = append(events, imageEvent{Type: "visibility_changed", Image: img, Old: old.Visibility, New: img.Visibility})
+		}
+		if old.Status != img.Status {
+			events = append(events, imageEvent{Type: "status_changed", Image: img, Old: old.Status, New: img.Status})
+		}
+	}
+
+	for id, img := range prev {
+		if !seen[id] {
+			events = append(events, imageEvent{Type: "deleted", Image: img, Old: img.Status})
+		}
+	}
+
+	sort.Sort(byImageID(events))
+	return events
+}
+
+// byImageID allows to sort events by image ID and event type
+type byImageID []imageEvent
+
+func (e byImageID) Len() int      { return len(e) }
+func (e byImageID) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byImageID) Less(i, j int) bool {
+	if e[i].Image.ID != e[j].Image.ID {
+		return e[i].Image.ID < e[j].Image.ID
+	}
+	return e[i].Type < e[j].Type
+}
+
+// diffImages calculates changes between previous snapshot and current list of images
+func diffImages(prev snapshot, curr []types.Image) types.Delta {
+	delta := types.Delta{}
+	seen := map[string]bool{}
+
+	for _, img := range curr {
+		seen[img.ID] = true
+		delta.Bytes += img.Size
+
+		old, found := prev[img.ID]
+		if !found {
+			delta.Created += 1
+			continue
+		}
+		if old.Status != img.Status {
+			delta.StatusChanged += 1
+		}
+	}
+
+	for id, img := range prev {
+		delta.Bytes -= img.Size
+		if !seen[id] {
+			delta.Deleted += 1
+		}
+	}
+
+	return delta
+}
+
+// snapshotKey identifies task which requested metrics for given tenant.
+// Snap does not provide task ID to collector, so it is derived from task configuration
+// and list of requested metrics. Tasks with identical configuration and metrics share snapshot.
+func snapshotKey(tenant string, metricTypes []plugin.MetricType) string {
+	items := []string{}
+	if cfg := metricTypes[0].Config(); cfg != nil {
+		for key, value := range cfg.Table() {
+			items = append(items, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	for _, metricType := range metricTypes {
+		items = append(items, metricType.Namespace().String())
+	}
+	sort.Strings(items)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s%v", tenant, items)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// advanceSnapshot returns changes of images since previous collection for the task and stores given listing
+// as its snapshot. First collection creates baseline and reports no changes. Snapshot is compared and stored
+// at once, so changes are reported by single one of overlapping collections, and snapshot of listing older
+// than stored one is not stored. It is called only when whole collection succeeded, so changes are reported
+// again by next collection if it failed. Snapshots not stored within snapshotTTL are evicted.
+func (c *collector) advanceSnapshot(key string, list listing) (types.Delta, []imageEvent) {
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for other, stored := range c.snapshots {
+		if now.Sub(stored.storedAt) > snapshotTTL {
+			delete(c.snapshots, other)
+		}
+	}
+
+	prev, found := c.snapshots[key]
+	if found && !list.listedAt.After(prev.listedAt) {
+		prev.storedAt = now
+		c.snapshots[key] = prev
+		return types.Delta{}, []imageEvent{}
+	}
+	c.snapshots[key] = storedSnapshot{images: newSnapshot(list.images), listedAt: list.listedAt, storedAt: now}
+
+	if !found {
+		return types.Delta{}, []imageEvent{}
+	}
+	return diffImages(prev.images, list.images), imageEvents(prev.images, list.images)
+}
+
+// eventMetrics creates metric for each event matching event type requested by metric type.
+// Dynamic event type element is replaced by type of the event.
+func eventMetrics(metricType plugin.MetricType, events []imageEvent) []plugin.MetricType {
+	metrics := []plugin.MetricType{}
+	requested := metricType.Namespace()
+	eventType := requested.Element(len(requested) - 1).Value
+
+	for _, event := range events {
+		if eventType != "*" && eventType != event.Type {
+			continue
+		}
+
+		namespace := make(core.Namespace, len(requested))
+		copy(namespace, requested)
+		namespace[len(namespace)-1].Value = event.Type
+
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: namespace,
+			Data_:      1,
+			Tags_:      event.Tags(),
+		})
+	}
+
+	return metrics
+}
@@ -0,0 +1,55 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"sync"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// catalogs keeps metadata definitions catalog of each Glance endpoint during single collection,
+// so catalog is read once per endpoint even if endpoint is shared by several tenants
+type catalogs struct {
+	mutex   sync.Mutex
+	results map[string]*catalog
+}
+
+// catalog is read by the first tenant which requests it, other tenants wait for its result
+type catalog struct {
+	once sync.Once
+	defs map[string]types.Metadefs
+	err  error
+}
+
+func newCatalogs() *catalogs {
+	return &catalogs{results: map[string]*catalog{}}
+}
+
+// get returns catalog read from endpoint of given URL, read is called only once for each endpoint
+func (c *catalogs) get(url string, read func() (map[string]types.Metadefs, error)) (map[string]types.Metadefs, error) {
+	c.mutex.Lock()
+	result, found := c.results[url]
+	if !found {
+		result = &catalog{}
+		c.results[url] = result
+	}
+	c.mutex.Unlock()
+
+	result.once.Do(func() {
+		result.defs, result.err = read()
+	})
+	return result.defs, result.err
+}
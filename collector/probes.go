@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-utilities/ns"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
+)
+
+// probeTypes lists requests sent to check health of Glance, ex. /intel/openstack/glance/probe/healthcheck/status
+var probeTypes = []string{"healthcheck", "versions", "images"}
+
+// probes keeps results of health probes of Glance endpoints during single collection,
+// so each endpoint is probed once even if it is shared by several tenants
+type probes struct {
+	mutex   sync.Mutex
+	results map[string]openstackintel.Probes
+}
+
+func newProbes() *probes {
+	return &probes{results: map[string]openstackintel.Probes{}}
+}
+
+// claim checks if endpoint of given URL was not probed yet and marks it as probed
+func (p *probes) claim(url string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, found := p.results[url]; found {
+		return false
+	}
+	p.results[url] = openstackintel.Probes{}
+	return true
+}
+
+func (p *probes) set(url string, results openstackintel.Probes) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.results[url] = results
+}
+
+// metrics returns value of given probe metric for each probed endpoint, tagged with URL of the endpoint
+func (p *probes) metrics(metricType plugin.MetricType) []plugin.MetricType {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	urls := []string{}
+	for url := range p.results {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	metrics := []plugin.MetricType{}
+	for _, url := range urls {
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: metricType.Namespace(),
+			Data_:      ns.GetValueByNamespace(p.results[url], metricType.Namespace().Strings()[4:]),
+			Tags_:      map[string]string{"endpoint": url},
+		})
+	}
+	return metrics
+}
+
+// isProbeMetric checks if metric describes health of Glance endpoints, ex. /intel/openstack/glance/probe/available
+func isProbeMetric(namespace []string) bool {
+	if len(namespace) < 5 || namespace[3] != "probe" {
+		return false
+	}
+	if len(namespace) == 5 {
+		return namespace[4] == "available"
+	}
+	for _, probeType := range probeTypes {
+		if len(namespace) == 6 && namespace[4] == probeType && (namespace[5] == "status" || namespace[5] == "latency") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// withoutRegion returns copy of metric type without region element, ex. /intel/openstack/glance/<region>/<tenant>/...
+// is changed to /intel/openstack/glance/<tenant>/..., so it can be collected like metric of single region
+func withoutRegion(metricType plugin.MetricType) plugin.MetricType {
+	namespace := metricType.Namespace()
+
+	stripped := make(core.Namespace, 0, len(namespace)-1)
+	stripped = append(stripped, namespace[:3]...)
+	stripped = append(stripped, namespace[4:]...)
+
+	metricType.Namespace_ = stripped
+	return metricType
+}
+
+// withRegion returns copy of metric with region element set to given region
+func withRegion(metric plugin.MetricType, region string) plugin.MetricType {
+	namespace := metric.Namespace()
+
+	element := core.NamespaceElement{Value: region, Name: "region", Description: "name of the region"}
+	regional := make(core.Namespace, 0, len(namespace)+1)
+	regional = append(regional, namespace[:3]...)
+	regional = append(regional, element)
+	regional = append(regional, namespace[3:]...)
+
+	metric.Namespace_ = regional
+	return metric
+}
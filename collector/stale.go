@@ -0,0 +1,60 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rackspace/gophercloud"
+)
+
+// staleError is returned when Glance rejected request because cached provider or dispatcher is not valid anymore
+type staleError struct {
+	err error
+}
+
+func (e *staleError) Error() string {
+	return e.err.Error()
+}
+
+// reauthFailed starts error returned by gophercloud when Keystone rejected re-authentication after Glance
+// responded with 401, ex. because password was rotated or pre-issued token cannot be renewed
+const reauthFailed = "Error trying to re-authenticate"
+
+// checkStale marks error of Glance version or image listing request as stale if Glance responded with 401 or 404,
+// or re-authentication after 401 failed, which happens when token cannot be renewed, project was removed
+// or negotiated API version was removed
+func checkStale(err error) error {
+	if e, ok := err.(*gophercloud.UnexpectedResponseCodeError); ok {
+		if e.Actual == http.StatusUnauthorized || e.Actual == http.StatusNotFound {
+			return &staleError{err: err}
+		}
+	}
+	if err != nil && strings.HasPrefix(err.Error(), reauthFailed) {
+		return &staleError{err: err}
+	}
+	return err
+}
+
+// regionError describes error of collection from given region, it remains stale if original error was stale
+func regionError(region string, err error) error {
+	regionErr := fmt.Errorf("Collection from region %s failed: %v", region, err)
+	if _, stale := err.(*staleError); stale {
+		return &staleError{err: regionErr}
+	}
+	return regionErr
+}
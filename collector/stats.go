@@ -0,0 +1,137 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
+)
+
+// collectorMetrics is used to generate metrics namespace for metrics of collector itself
+type collectorMetrics struct {
+	HTTP     openstackintel.Stats `json:"http"`
+	Keystone keystoneStats        `json:"keystone"`
+	Glance   glanceStats          `json:"glance"`
+	Cache    cacheStats           `json:"cache"`
+	Errors   errorStats           `json:"errors"`
+}
+
+type keystoneStats struct {
+	// Logins is number of Keystone logins since plugin start
+	Logins int64 `json:"logins"`
+	// LoginDuration is duration of the last login in seconds
+	LoginDuration float64 `json:"login_duration"`
+}
+
+type glanceStats struct {
+	// Listings is number of image listings requested from Glance since plugin start
+	Listings int64 `json:"listings"`
+	// ListingDuration is duration of the last image listing in seconds
+	ListingDuration float64 `json:"listing_duration"`
+	Pages           int64   `json:"pages"`
+	Images          int64   `json:"images"`
+}
+
+type cacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// errorStats counts failed logins, version negotiations and image listings by type of error
+type errorStats struct {
+	Timeout      int64 `json:"timeout"`
+	Unauthorized int64 `json:"unauthorized"`
+	NotFound     int64 `json:"not_found"`
+	ServerError  int64 `json:"server_error"`
+	Other        int64 `json:"other"`
+}
+
+// selfGroups lists groups of metrics of collector itself, ex. /intel/openstack/glance/collector/keystone/logins
+var selfGroups = []string{"http", "keystone", "glance", "cache", "errors"}
+
+// getStats returns current values of collector metrics
+func (c *collector) getStats() collectorMetrics {
+	c.mutex.Lock()
+	stats := c.stats
+	c.mutex.Unlock()
+
+	listings := openstackintel.GetListingStats()
+	stats.HTTP = openstackintel.GetStats()
+	stats.Glance.Pages = listings.Pages
+	stats.Glance.Images = listings.Images
+	return stats
+}
+
+// countLogin records duration of Keystone login
+func (c *collector) countLogin(started time.Time, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.Keystone.Logins++
+	c.stats.Keystone.LoginDuration = time.Since(started).Seconds()
+	c.countError(err)
+}
+
+// countListing records duration of image listing requested from Glance
+func (c *collector) countListing(started time.Time, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.Glance.Listings++
+	c.stats.Glance.ListingDuration = time.Since(started).Seconds()
+	c.stats.Cache.Misses++
+	c.countError(err)
+}
+
+// countCacheHit records image listing taken from cache
+func (c *collector) countCacheHit() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.Cache.Hits++
+}
+
+// countError records error by its type, it has to be called with mutex held
+func (c *collector) countError(err error) {
+	if err == nil {
+		return
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		c.stats.Errors.Timeout++
+		return
+	}
+
+	if e, ok := err.(*gophercloud.UnexpectedResponseCodeError); ok {
+		switch {
+		case e.Actual == http.StatusUnauthorized || e.Actual == http.StatusForbidden:
+			c.stats.Errors.Unauthorized++
+		case e.Actual == http.StatusNotFound:
+			c.stats.Errors.NotFound++
+		case e.Actual >= http.StatusInternalServerError:
+			c.stats.Errors.ServerError++
+		default:
+			c.stats.Errors.Other++
+		}
+		return
+	}
+
+	c.stats.Errors.Other++
+}
@@ -0,0 +1,144 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
+)
+
+// tenantIndex returns position of tenant element in namespace of tenant metrics,
+// ex. /intel/openstack/glance/<tenant>/... or /intel/openstack/glance/<region>/<tenant>/...
+func tenantIndex(cfg interface{}) int {
+	if _, found := configRegions(cfg); found {
+		return 4
+	}
+	return 3
+}
+
+// tenants returns sorted names of tenants to collect metrics from. Tenant given in namespace is collected as is,
+// dynamic tenant element means configured tenant, or all tenants available for the user if no tenant is configured.
+// Tenants are discovered also when only metrics calculated across all tenants are requested.
+func tenants(t target, metricTypes []plugin.MetricType, index int) ([]string, error) {
+	names := map[string]bool{}
+	discover := false
+
+	requested := []string{}
+	for _, metricType := range metricTypes {
+		requested = append(requested, metricType.Namespace().Element(index).Value)
+	}
+	if len(requested) == 0 {
+		requested = append(requested, "*")
+	}
+
+	if t.auth.IsProjectBound() {
+		return boundTenant(t, requested)
+	}
+
+	for _, tenant := range requested {
+		switch {
+		case tenant != "*":
+			names[tenant] = true
+		case t.auth.TenantName != "":
+			names[t.auth.TenantName] = true
+		default:
+			discover = true
+		}
+	}
+
+	if discover {
+		available, err := openstackintel.Common{Endpoint: t.endpoint}.GetTenants(t.auth)
+		if err != nil {
+			return nil, err
+		}
+		for _, tenant := range available {
+			names[tenant.Name] = true
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No tenants available for user %s, set tenant in configuration", t.auth.Username)
+	}
+
+	sorted := []string{}
+	for tenant := range names {
+		sorted = append(sorted, tenant)
+	}
+	sort.Strings(sorted)
+
+	return sorted, nil
+}
+
+// boundTenant returns configured tenant of credentials bound to single project, which only names metrics,
+// as credentials cannot be scoped to any other tenant
+func boundTenant(t target, requested []string) ([]string, error) {
+	tenant := t.auth.TenantName
+	if tenant == "" {
+		return nil, fmt.Errorf("Credentials are bound to single project, set tenant in configuration to name its metrics")
+	}
+
+	for _, name := range requested {
+		if name != "*" && name != tenant {
+			return nil, fmt.Errorf("Credentials are bound to project %s, metrics of tenant %s cannot be collected", tenant, name)
+		}
+	}
+
+	return []string{tenant}, nil
+}
+
+// tenantTarget returns target of given tenant, credentials scoped to other than configured tenant are scoped by name
+func tenantTarget(t target, tenant string) target {
+	if t.key == "" {
+		t.key = tenant
+	} else {
+		t.key = t.key + "/" + tenant
+	}
+
+	if tenant != t.auth.TenantName {
+		t.auth.TenantName = tenant
+		t.auth.TenantID = ""
+	}
+
+	return t
+}
+
+// tenantMetricTypes returns metric types requested for given tenant, with dynamic tenant element set to its name
+// Metric type requested both for given tenant and for all tenants is returned once.
+func tenantMetricTypes(t target, tenant string, metricTypes []plugin.MetricType, index int) []plugin.MetricType {
+	requested := []plugin.MetricType{}
+	seen := map[string]bool{}
+	for _, metricType := range metricTypes {
+		value := metricType.Namespace().Element(index).Value
+		if value == "*" && (t.auth.TenantName == "" || t.auth.TenantName == tenant) {
+			namespace := make(core.Namespace, len(metricType.Namespace()))
+			copy(namespace, metricType.Namespace())
+			namespace[index].Value = tenant
+			metricType.Namespace_ = namespace
+		} else if value != tenant {
+			continue
+		}
+
+		if key := metricType.Namespace().String(); !seen[key] {
+			seen[key] = true
+			requested = append(requested, metricType)
+		}
+	}
+	return requested
+}
@@ -0,0 +1,116 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// unusedImages counts images and bytes used by images which are not referenced in usage
+func unusedImages(imgs []types.Image, usage map[string]int) types.Images {
+	unused := types.Images{}
+	for _, img := range imgs {
+		if usage[img.ID] == 0 {
+			unused.Count += 1
+			unused.Bytes += img.Size
+		}
+	}
+	return unused
+}
+
+// volumeImages counts images and bytes used by images which are referenced by volumes.
+// Images marked for deletion which are still referenced are counted separately.
+func volumeImages(imgs []types.Image, usage map[string]int) types.VolumeImages {
+	used := types.VolumeImages{}
+	for _, img := range imgs {
+		if usage[img.ID] == 0 {
+			continue
+		}
+		used.Count += 1
+		used.Bytes += img.Size
+		if img.Status == "pending_delete" || img.Status == "deleted" {
+			used.PendingDelete += 1
+		}
+	}
+	return used
+}
+
+// usageMetrics creates metric for each image matching image ID requested by metric type,
+// ex. /intel/openstack/glance/<tenant>/image/<image_id>/in_use_by_servers.
+// Dynamic image ID element is replaced by ID of the image.
+func usageMetrics(metricType plugin.MetricType, imgs []types.Image, usage map[string]int) []plugin.MetricType {
+	metrics := []plugin.MetricType{}
+	requested := metricType.Namespace()
+	imageID := requested.Element(5).Value
+
+	for _, img := range imgs {
+		if imageID != "*" && imageID != img.ID {
+			continue
+		}
+
+		namespace := make(core.Namespace, len(requested))
+		copy(namespace, requested)
+		namespace[5].Value = img.ID
+
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: time.Now(),
+			Namespace_: namespace,
+			Data_:      usage[img.ID],
+			Tags_:      map[string]string{"image_name": img.Name},
+		})
+	}
+
+	return metrics
+}
+
+// usages keeps number of servers or volumes created from each image during single collection,
+// so servers or volumes of all tenants are listed once per endpoint even if endpoint is shared by several tenants
+type usages struct {
+	mutex   sync.Mutex
+	results map[string]*usage
+}
+
+// usage is listed by the first tenant which requests it, other tenants wait for its result
+type usage struct {
+	once   sync.Once
+	counts map[string]int
+	err    error
+}
+
+func newUsages() *usages {
+	return &usages{results: map[string]*usage{}}
+}
+
+// get returns usage of images listed from endpoint of given URL, list is called only once for each endpoint
+func (u *usages) get(url string, list func() (map[string]int, error)) (map[string]int, error) {
+	u.mutex.Lock()
+	result, found := u.results[url]
+	if !found {
+		result = &usage{}
+		u.results[url] = result
+	}
+	u.mutex.Unlock()
+
+	result.once.Do(func() {
+		result.counts, result.err = list()
+	})
+	return result.counts, result.err
+}
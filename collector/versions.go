@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
+)
+
+// apis keeps Glance API versions available at each Glance endpoint during single collection,
+// together with version negotiated by the collector, ex. /intel/openstack/glance/api/selected
+type apis struct {
+	mutex     sync.Mutex
+	available map[string][]types.ApiVersion
+	selected  map[string]string
+}
+
+func newAPIs() *apis {
+	return &apis{available: map[string][]types.ApiVersion{}, selected: map[string]string{}}
+}
+
+// claim checks if versions of endpoint of given URL were not discovered yet and marks them as discovered
+func (a *apis) claim(url string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, found := a.available[url]; found {
+		return false
+	}
+	a.available[url] = []types.ApiVersion{}
+	return true
+}
+
+func (a *apis) set(url string, selected string, available []types.ApiVersion) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.available[url] = available
+	a.selected[url] = selected
+}
+
+// metrics returns selected version or status of each matching available version of each Glance endpoint,
+// tagged with URL of the endpoint and link of the version
+func (a *apis) metrics(metricType plugin.MetricType) []plugin.MetricType {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	urls := []string{}
+	for url := range a.available {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	namespace := metricType.Namespace().Strings()
+	metrics := []plugin.MetricType{}
+	for _, url := range urls {
+		if namespace[4] == "selected" {
+			metric := plugin.MetricType{
+				Timestamp_: time.Now(),
+				Namespace_: metricType.Namespace(),
+				Data_:      a.selected[url],
+				Tags_:      map[string]string{"endpoint": url},
+			}
+			for _, version := range a.available[url] {
+				if version.ID == a.selected[url] {
+					metric.Tags_["link"] = version.Link
+				}
+			}
+			metrics = append(metrics, metric)
+			continue
+		}
+
+		for _, version := range a.available[url] {
+			if namespace[5] != "*" && namespace[5] != version.ID {
+				continue
+			}
+			versionNamespace := make(core.Namespace, len(metricType.Namespace()))
+			copy(versionNamespace, metricType.Namespace())
+			versionNamespace[5].Value = version.ID
+
+			metrics = append(metrics, plugin.MetricType{
+				Timestamp_: time.Now(),
+				Namespace_: versionNamespace,
+				Data_:      version.Status,
+				Tags_:      map[string]string{"endpoint": url, "link": version.Link},
+			})
+		}
+	}
+	return metrics
+}
+
+// isAPIMetric checks if metric describes Glance API versions, ex. /intel/openstack/glance/api/versions/v2.3/status
+func isAPIMetric(namespace []string) bool {
+	if len(namespace) < 5 || namespace[3] != "api" {
+		return false
+	}
+	return (len(namespace) == 5 && namespace[4] == "selected") ||
+		(len(namespace) == 7 && namespace[4] == "versions" && namespace[6] == "status")
+}
@@ -0,0 +1,380 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+	"github.com/rackspace/gophercloud/openstack"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/identity/v3/tokens"
+)
+
+// tokenExpiryMargin is minimal remaining lifetime of pre-issued token, token expiring sooner is treated as expired
+const tokenExpiryMargin = time.Minute
+
+// AuthOptions holds identity endpoint and credentials used to authenticate in Keystone
+type AuthOptions struct {
+	IdentityEndpoint string
+	Username         string
+	Password         string
+	TenantName       string
+	TenantID         string
+
+	// DomainName and DomainID are used as both user and project domain, unless specific domain is set
+	DomainName        string
+	DomainID          string
+	UserDomainName    string
+	UserDomainID      string
+	ProjectDomainName string
+	ProjectDomainID   string
+
+	// application credentials, tokens and trusts are supported only by Keystone v3
+	ApplicationCredentialID     string
+	ApplicationCredentialName   string
+	ApplicationCredentialSecret string
+	TokenID                     string
+	TrustID                     string
+
+	// Transport configures HTTP client used for Keystone and services found in its catalog
+	Transport TransportOptions
+}
+
+// TokenExpiredError is returned when pre-issued token expired or expires soon, as it cannot be renewed by the plugin
+type TokenExpiredError struct {
+	ExpiresAt time.Time
+}
+
+func (e *TokenExpiredError) Error() string {
+	if e.ExpiresAt.IsZero() {
+		return "Token expired or is not valid, new token has to be provided"
+	}
+	return fmt.Sprintf("Token expired or expires soon (valid until %s), new token has to be provided", e.ExpiresAt.Format(time.RFC3339))
+}
+
+// Validate checks if options describe exactly one authentication method
+func (opts AuthOptions) Validate() error {
+	if opts.IdentityEndpoint == "" {
+		return fmt.Errorf("Invalid auth options: endpoint is required")
+	}
+
+	domains := [][]string{
+		{"domain_name", opts.DomainName, "domain_id", opts.DomainID},
+		{"user_domain_name", opts.UserDomainName, "user_domain_id", opts.UserDomainID},
+		{"project_domain_name", opts.ProjectDomainName, "project_domain_id", opts.ProjectDomainID},
+	}
+	for _, domain := range domains {
+		if domain[1] != "" && domain[3] != "" {
+			return fmt.Errorf("Invalid auth options: %s and %s cannot be used together", domain[0], domain[2])
+		}
+	}
+
+	if opts.TenantID != "" && (opts.ProjectDomainName != "" || opts.ProjectDomainID != "") {
+		return fmt.Errorf("Invalid auth options: project domain cannot be used with project_id, which is unique across domains")
+	}
+
+	if opts.isApplicationCredential() {
+		if opts.ApplicationCredentialSecret == "" {
+			return fmt.Errorf("Invalid auth options: application_credential_secret is required")
+		}
+		if opts.ApplicationCredentialID != "" && opts.ApplicationCredentialName != "" {
+			return fmt.Errorf("Invalid auth options: application_credential_id and application_credential_name cannot be used together")
+		}
+		if opts.ApplicationCredentialID == "" && opts.ApplicationCredentialName == "" {
+			return fmt.Errorf("Invalid auth options: application_credential_id or application_credential_name is required")
+		}
+		if opts.Password != "" {
+			return fmt.Errorf("Invalid auth options: password cannot be used with application credential")
+		}
+		if opts.TokenID != "" {
+			return fmt.Errorf("Invalid auth options: token cannot be used with application credential")
+		}
+		if opts.TrustID != "" {
+			return fmt.Errorf("Invalid auth options: trust_id cannot be used with application credential")
+		}
+		if opts.TenantID != "" || opts.ProjectDomainName != "" || opts.ProjectDomainID != "" {
+			return fmt.Errorf("Invalid auth options: application credential is bound to project, project_id and project domain cannot be used")
+		}
+		if opts.ApplicationCredentialID != "" && opts.Username != "" {
+			return fmt.Errorf("Invalid auth options: user cannot be used with application_credential_id")
+		}
+		if opts.ApplicationCredentialName != "" {
+			if opts.Username == "" {
+				return fmt.Errorf("Invalid auth options: user is required with application_credential_name")
+			}
+			if id, name := opts.userDomain(); id == "" && name == "" {
+				return fmt.Errorf("Invalid auth options: user domain is required with application_credential_name")
+			}
+		}
+		return nil
+	}
+
+	if opts.TrustID != "" && (opts.TenantID != "" || opts.ProjectDomainName != "" || opts.ProjectDomainID != "") {
+		return fmt.Errorf("Invalid auth options: trust_id cannot be used with project_id or project domain")
+	}
+
+	if opts.TokenID != "" {
+		if opts.Username != "" || opts.Password != "" {
+			return fmt.Errorf("Invalid auth options: user and password cannot be used with token")
+		}
+		if opts.isRescoped() {
+			return opts.validateProjectScope()
+		}
+		return nil
+	}
+
+	if opts.Username == "" || opts.Password == "" {
+		return fmt.Errorf("Invalid auth options: user and password are required")
+	}
+
+	if opts.TrustID != "" {
+		if id, name := opts.userDomain(); id == "" && name == "" {
+			return fmt.Errorf("Invalid auth options: user domain is required with trust_id")
+		}
+		return nil
+	}
+
+	if opts.requiresV3() {
+		if id, name := opts.userDomain(); id == "" && name == "" {
+			return fmt.Errorf("Invalid auth options: user domain is required with project_id or project domain")
+		}
+		return opts.validateProjectScope()
+	}
+
+	return nil
+}
+
+// validateProjectScope checks if project is identified by ID or by name and domain
+func (opts AuthOptions) validateProjectScope() error {
+	if opts.TenantID != "" {
+		return nil
+	}
+	if opts.TenantName == "" {
+		return fmt.Errorf("Invalid auth options: project_id or tenant is required")
+	}
+	if id, name := opts.projectDomain(); id == "" && name == "" {
+		return fmt.Errorf("Invalid auth options: project domain is required to scope by tenant name")
+	}
+	return nil
+}
+
+// userDomain returns ID and name of the user domain, generic domain is used if specific one is not set
+func (opts AuthOptions) userDomain() (string, string) {
+	if opts.UserDomainID != "" || opts.UserDomainName != "" {
+		return opts.UserDomainID, opts.UserDomainName
+	}
+	return opts.DomainID, opts.DomainName
+}
+
+// projectDomain returns ID and name of the project domain, generic domain is used if specific one is not set
+func (opts AuthOptions) projectDomain() (string, string) {
+	if opts.ProjectDomainID != "" || opts.ProjectDomainName != "" {
+		return opts.ProjectDomainID, opts.ProjectDomainName
+	}
+	return opts.DomainID, opts.DomainName
+}
+
+// isRescoped checks if pre-issued token should be exchanged for token scoped to configured project
+func (opts AuthOptions) isRescoped() bool {
+	return opts.TokenID != "" && opts.TrustID == "" &&
+		(opts.TenantID != "" || opts.ProjectDomainName != "" || opts.ProjectDomainID != "")
+}
+
+// IsProjectBound checks if credentials are bound to single project and cannot be scoped to other projects,
+// ex. application credential, trust or pre-issued token. Token can be rescoped to other project only by its name
+// and project domain, token rescoped by project ID is bound to that project.
+func (opts AuthOptions) IsProjectBound() bool {
+	if opts.isApplicationCredential() || opts.TrustID != "" {
+		return true
+	}
+	return opts.TokenID != "" && (opts.TenantID != "" || (opts.ProjectDomainName == "" && opts.ProjectDomainID == ""))
+}
+
+func (opts AuthOptions) isApplicationCredential() bool {
+	return opts.ApplicationCredentialID != "" || opts.ApplicationCredentialName != "" || opts.ApplicationCredentialSecret != ""
+}
+
+// requiresV3 checks if options use authentication methods or scope which are not supported by gophercloud.
+// Gophercloud uses single domain for both user and project, so separate domains require Keystone v3 as well.
+func (opts AuthOptions) requiresV3() bool {
+	return opts.isApplicationCredential() || opts.TokenID != "" || opts.TrustID != "" || opts.TenantID != "" ||
+		opts.UserDomainName != "" || opts.UserDomainID != "" || opts.ProjectDomainName != "" || opts.ProjectDomainID != ""
+}
+
+// authenticatedV3Client creates provider client authenticated in Keystone v3
+func authenticatedV3Client(opts AuthOptions) (*gophercloud.ProviderClient, error) {
+	provider, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// pre-issued token is used as is, unless it has to be rescoped, it only needs to be validated to get service catalog
+	if opts.TokenID != "" && opts.TrustID == "" && !opts.isRescoped() {
+		err = validateToken(provider, opts.TokenID)
+	} else {
+		domainID, domainName := opts.userDomain()
+		err = authenticateV3(provider, tokens.AuthOptions{
+			Username:                    opts.Username,
+			Password:                    opts.Password,
+			DomainID:                    domainID,
+			DomainName:                  domainName,
+			TokenID:                     opts.TokenID,
+			ApplicationCredentialID:     opts.ApplicationCredentialID,
+			ApplicationCredentialName:   opts.ApplicationCredentialName,
+			ApplicationCredentialSecret: opts.ApplicationCredentialSecret,
+			TrustID:                     opts.TrustID,
+			Scope:                       opts.scope(),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// scope returns project scope of token, application credential and trust are already scoped
+func (opts AuthOptions) scope() *tokens.Scope {
+	if opts.isApplicationCredential() || opts.TrustID != "" {
+		return nil
+	}
+	if opts.TenantID != "" {
+		return &tokens.Scope{ProjectID: opts.TenantID}
+	}
+	domainID, domainName := opts.projectDomain()
+	return &tokens.Scope{ProjectName: opts.TenantName, DomainID: domainID, DomainName: domainName}
+}
+
+// authenticateV3 authenticates provider with methods which are not supported by gophercloud
+// and sets endpoint locator based on received service catalog
+func authenticateV3(client *gophercloud.ProviderClient, opts tokens.AuthOptions) error {
+	result := tokens.Create(identityV3(client), opts)
+
+	token, err := result.ExtractToken()
+	if err != nil {
+		if opts.TokenID != "" && isTokenRejected(err) {
+			return &TokenExpiredError{}
+		}
+		return err
+	}
+
+	if opts.TokenID != "" && time.Now().Add(tokenExpiryMargin).After(token.ExpiresAt) {
+		return &TokenExpiredError{ExpiresAt: token.ExpiresAt}
+	}
+
+	catalog, err := result.ExtractServiceCatalog()
+	if err != nil {
+		return err
+	}
+
+	client.TokenID = token.ID
+	client.ReauthFunc = func() error {
+		client.TokenID = ""
+		return authenticateV3(client, opts)
+	}
+	client.EndpointLocator = func(eo gophercloud.EndpointOpts) (string, error) {
+		return openstack.V3EndpointURL(catalog, eo)
+	}
+
+	return nil
+}
+
+// validateToken checks if pre-issued token is still valid and sets it in provider.
+// Such token cannot be renewed, so re-authentication fails with TokenExpiredError.
+func validateToken(client *gophercloud.ProviderClient, tokenID string) error {
+	result := tokens.Get(identityV3(client), tokenID)
+
+	token, err := result.ExtractToken()
+	if err != nil {
+		if isTokenRejected(err) {
+			return &TokenExpiredError{}
+		}
+		return err
+	}
+
+	if time.Now().Add(tokenExpiryMargin).After(token.ExpiresAt) {
+		return &TokenExpiredError{ExpiresAt: token.ExpiresAt}
+	}
+
+	catalog, err := result.ExtractServiceCatalog()
+	if err != nil {
+		return err
+	}
+
+	client.TokenID = tokenID
+	client.ReauthFunc = func() error {
+		return &TokenExpiredError{ExpiresAt: token.ExpiresAt}
+	}
+	client.EndpointLocator = func(eo gophercloud.EndpointOpts) (string, error) {
+		return openstack.V3EndpointURL(catalog, eo)
+	}
+
+	return nil
+}
+
+// newClient creates unauthenticated provider client which uses HTTP client configured with transport options
+func newClient(opts AuthOptions) (*gophercloud.ProviderClient, error) {
+	httpClient, err := opts.Transport.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := openstack.NewClient(opts.IdentityEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	provider.HTTPClient = httpClient
+
+	return provider, nil
+}
+
+// identityV3 creates Keystone v3 client which does not share token and re-authentication with given provider,
+// so failed authentication request is not retried in a loop
+func identityV3(client *gophercloud.ProviderClient) *gophercloud.ServiceClient {
+	provider := &gophercloud.ProviderClient{
+		IdentityBase:     client.IdentityBase,
+		IdentityEndpoint: client.IdentityEndpoint,
+		HTTPClient:       client.HTTPClient,
+		UserAgent:        client.UserAgent,
+	}
+
+	return &gophercloud.ServiceClient{ProviderClient: provider, Endpoint: identityV3Endpoint(client)}
+}
+
+// identityV3Endpoint returns Keystone v3 endpoint based on configured identity endpoint,
+// which may point to Keystone root or to specific API version
+func identityV3Endpoint(client *gophercloud.ProviderClient) string {
+	endpoint := client.IdentityEndpoint
+	switch {
+	case endpoint == "":
+		return openstack.NewIdentityV3(client).Endpoint
+	case strings.Contains(endpoint, "/v3"):
+		return endpoint
+	default:
+		return strings.TrimSuffix(endpoint, "v2.0/") + "v3/"
+	}
+}
+
+// isTokenRejected checks if Keystone rejected token, which happens when token expired or was revoked
+func isTokenRejected(err error) bool {
+	if e, ok := err.(*gophercloud.UnexpectedResponseCodeError); ok {
+		return e.Actual == http.StatusUnauthorized || e.Actual == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,325 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/rackspace/gophercloud"
+)
+
+type AuthSuite struct {
+	suite.Suite
+	Token  string
+	Server *httptest.Server
+	// Request is body of the last token create request
+	Request map[string]interface{}
+}
+
+func (s *AuthSuite) SetupSuite() {
+	s.Token = "5f4e3d2c1b0a49f8a7b6c5d4e3f2a1b0"
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/auth/tokens" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		expiresAt := time.Now().Add(time.Hour)
+		switch r.Method {
+		case "POST":
+			s.Request = map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&s.Request)
+			w.Header().Add("X-Subject-Token", s.Token)
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			switch r.Header.Get("X-Subject-Token") {
+			case "valid":
+			case "expiring":
+				expiresAt = time.Now().Add(10 * time.Second)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Add("X-Subject-Token", r.Header.Get("X-Subject-Token"))
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}
+
+		fmt.Fprintf(w, `
+			{
+				"token": {
+					"expires_at": "%s",
+					"catalog": [
+						{
+							"id": "3ffe125aa59547029ed774c10b932349",
+							"name": "glance",
+							"type": "image",
+							"endpoints": [
+								{
+									"id": "7c8d9ec05e6b4c2fa3b1b6f1d0f7b8a1",
+									"interface": "public",
+									"region": "RegionOne",
+									"url": "%s/glance"
+								}
+							]
+						}
+					]
+				}
+			}
+		`, expiresAt.UTC().Format(time.RFC3339Nano), s.Server.URL)
+	}))
+}
+
+func (s *AuthSuite) TearDownSuite() {
+	s.Server.Close()
+}
+
+func (s *AuthSuite) TestValidate() {
+	Convey("Given auth options", s.T(), func() {
+		valid := map[string]AuthOptions{
+			"password": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret"},
+			"application credential ID": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialID: "id", ApplicationCredentialSecret: "secret"},
+			"token": {
+				IdentityEndpoint: s.Server.URL, TokenID: "token"},
+			"token rescoped to project ID": {
+				IdentityEndpoint: s.Server.URL, TokenID: "token", TenantID: "project"},
+			"project ID": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", UserDomainName: "users",
+				TenantID: "project"},
+			"separate user and project domains": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", UserDomainName: "users",
+				TenantName: "tenant", ProjectDomainID: "projects"},
+			"trust with token": {
+				IdentityEndpoint: s.Server.URL, TokenID: "token", TrustID: "trust"},
+			"trust with password": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", DomainName: "Default", TrustID: "trust"},
+			"application credential name": {
+				IdentityEndpoint: s.Server.URL, Username: "me", DomainName: "Default",
+				ApplicationCredentialName: "name", ApplicationCredentialSecret: "secret"},
+		}
+		invalid := map[string]AuthOptions{
+			"missing endpoint": {
+				Username: "me", Password: "secret"},
+			"missing password": {
+				IdentityEndpoint: s.Server.URL, Username: "me"},
+			"missing secret": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialID: "id"},
+			"secret only": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialSecret: "secret"},
+			"both ID and name": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialID: "id", ApplicationCredentialName: "name",
+				ApplicationCredentialSecret: "secret"},
+			"application credential with password": {
+				IdentityEndpoint: s.Server.URL, Password: "secret", ApplicationCredentialID: "id",
+				ApplicationCredentialSecret: "secret"},
+			"both domain name and ID": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", DomainName: "Default", DomainID: "default"},
+			"both user domain name and ID": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", UserDomainName: "Default",
+				UserDomainID: "default", TenantID: "project"},
+			"project ID with project domain": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", UserDomainName: "users",
+				TenantID: "project", ProjectDomainName: "projects"},
+			"project ID without user domain": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", TenantID: "project"},
+			"user domain without project domain": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", UserDomainName: "users",
+				TenantName: "tenant"},
+			"project ID with application credential": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialID: "id", ApplicationCredentialSecret: "secret",
+				TenantID: "project"},
+			"trust with project ID": {
+				IdentityEndpoint: s.Server.URL, TokenID: "token", TrustID: "trust", TenantID: "project"},
+			"token with password": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", TokenID: "token"},
+			"trust without trustee": {
+				IdentityEndpoint: s.Server.URL, TrustID: "trust"},
+			"trust without user domain": {
+				IdentityEndpoint: s.Server.URL, Username: "me", Password: "secret", TrustID: "trust"},
+			"trust with application credential": {
+				IdentityEndpoint: s.Server.URL, ApplicationCredentialID: "id", ApplicationCredentialSecret: "secret",
+				TrustID: "trust"},
+			"name without user domain": {
+				IdentityEndpoint: s.Server.URL, Username: "me", ApplicationCredentialName: "name",
+				ApplicationCredentialSecret: "secret"},
+		}
+
+		Convey("When options describe single authentication method", func() {
+			Convey("Then no error is returned", func() {
+				for _, opts := range valid {
+					So(opts.Validate(), ShouldBeNil)
+				}
+			})
+		})
+
+		Convey("When options are incomplete or mixed", func() {
+			Convey("Then error is returned", func() {
+				for _, opts := range invalid {
+					So(opts.Validate(), ShouldNotBeNil)
+				}
+			})
+		})
+	})
+}
+
+func (s *AuthSuite) TestAuthenticateApplicationCredential() {
+	Convey("Given application credential", s.T(), func() {
+		opts := AuthOptions{
+			IdentityEndpoint:            s.Server.URL + "/v3/",
+			ApplicationCredentialID:     "423f19a4ac1e4f48bbb4180756e6eb6c",
+			ApplicationCredentialSecret: "rEaqvJka48mpv",
+		}
+
+		Convey("When Authenticate is called", func() {
+			provider, err := Authenticate(opts)
+
+			Convey("Then provider is authenticated with Keystone v3 token", func() {
+				So(err, ShouldBeNil)
+				So(provider.TokenID, ShouldEqual, s.Token)
+			})
+
+			Convey("and endpoints are located in received catalog", func() {
+				url, err := provider.EndpointLocator(gophercloud.EndpointOpts{Type: "image", Availability: gophercloud.AvailabilityPublic})
+				So(err, ShouldBeNil)
+				So(url, ShouldEqual, s.Server.URL+"/glance/")
+			})
+		})
+	})
+}
+
+func (s *AuthSuite) TestAuthenticateToken() {
+	Convey("Given pre-issued token", s.T(), func() {
+		Convey("When token is valid", func() {
+			provider, err := Authenticate(AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "valid"})
+
+			Convey("Then provider uses given token", func() {
+				So(err, ShouldBeNil)
+				So(provider.TokenID, ShouldEqual, "valid")
+			})
+
+			Convey("and re-authentication reports expired token", func() {
+				err := provider.ReauthFunc()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Token expired")
+			})
+		})
+
+		Convey("When token expires soon", func() {
+			_, err := Authenticate(AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "expiring"})
+
+			Convey("Then token expired error is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &TokenExpiredError{})
+				So(err.(*TokenExpiredError).ExpiresAt.IsZero(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When token is rejected by Keystone", func() {
+			_, err := Authenticate(AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "revoked"})
+
+			Convey("Then token expired error is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &TokenExpiredError{})
+			})
+		})
+	})
+}
+
+func (s *AuthSuite) TestAuthenticateTrust() {
+	Convey("Given token and trust ID", s.T(), func() {
+		opts := AuthOptions{IdentityEndpoint: s.Server.URL + "/v3/", TokenID: "valid", TrustID: "trust"}
+
+		Convey("When Authenticate is called", func() {
+			provider, err := Authenticate(opts)
+
+			Convey("Then token is exchanged for trust scoped token", func() {
+				So(err, ShouldBeNil)
+				So(provider.TokenID, ShouldEqual, s.Token)
+			})
+		})
+	})
+}
+
+func (s *AuthSuite) TestAuthenticateProjectScope() {
+	Convey("Given user and project in different domains", s.T(), func() {
+		opts := AuthOptions{
+			IdentityEndpoint:  s.Server.URL + "/v3/",
+			Username:          "me",
+			Password:          "secret",
+			TenantName:        "tenant",
+			UserDomainName:    "users",
+			ProjectDomainName: "projects",
+		}
+
+		Convey("When Authenticate is called", func() {
+			provider, err := Authenticate(opts)
+
+			Convey("Then provider is authenticated", func() {
+				So(err, ShouldBeNil)
+				So(provider.TokenID, ShouldEqual, s.Token)
+			})
+
+			Convey("and user and project are looked up in their own domains", func() {
+				auth := s.Request["auth"].(map[string]interface{})
+				identity := auth["identity"].(map[string]interface{})
+				user := identity["password"].(map[string]interface{})["user"].(map[string]interface{})
+				So(user["domain"], ShouldResemble, map[string]interface{}{"name": "users"})
+
+				project := auth["scope"].(map[string]interface{})["project"].(map[string]interface{})
+				So(project["name"], ShouldEqual, "tenant")
+				So(project["domain"], ShouldResemble, map[string]interface{}{"name": "projects"})
+			})
+		})
+	})
+
+	Convey("Given project ID", s.T(), func() {
+		opts := AuthOptions{
+			IdentityEndpoint: s.Server.URL + "/v3/",
+			Username:         "me",
+			Password:         "secret",
+			TenantName:       "tenant",
+			TenantID:         "97ea299c37bb4e04b3779039ea8aba44",
+			DomainID:         "default",
+		}
+
+		Convey("When Authenticate is called", func() {
+			_, err := Authenticate(opts)
+
+			Convey("Then token is scoped by project ID", func() {
+				So(err, ShouldBeNil)
+				auth := s.Request["auth"].(map[string]interface{})
+				So(auth["scope"], ShouldResemble, map[string]interface{}{
+					"project": map[string]interface{}{"id": "97ea299c37bb4e04b3779039ea8aba44"},
+				})
+			})
+		})
+	})
+}
+
+func TestAuthSuite(t *testing.T) {
+	authTestSuite := new(AuthSuite)
+	suite.Run(t, authTestSuite)
+}
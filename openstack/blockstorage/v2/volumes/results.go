@@ -0,0 +1,75 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumes
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Volume represents Cinder volume
+type Volume struct {
+	ID       string `json:"id" mapstructure:"id"`
+	Name     string `json:"name" mapstructure:"name"`
+	TenantID string `json:"os-vol-tenant-attr:tenant_id" mapstructure:"os-vol-tenant-attr:tenant_id"`
+	Status   string `json:"status" mapstructure:"status"`
+	// Size of the volume in GB
+	Size int `json:"size" mapstructure:"size"`
+	// VolumeImageMetadata is set only for volumes created from image
+	VolumeImageMetadata map[string]interface{} `json:"volume_image_metadata" mapstructure:"volume_image_metadata"`
+}
+
+// ImageID returns ID of the image volume was created from or empty string
+func (v Volume) ImageID() string {
+	id, _ := v.VolumeImageMetadata["image_id"].(string)
+	return id
+}
+
+// Link represents pagination link returned by Cinder
+type Link struct {
+	Href string `json:"href" mapstructure:"href"`
+	Rel  string `json:"rel" mapstructure:"rel"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+	volumes []Volume
+}
+
+// Extract will get the Volume objects out of the ListResult object.
+func (r ListResult) Extract() ([]Volume, error) {
+	return r.volumes, r.Err
+}
+
+// extractPage decodes single page of volumes together with link to next page
+func (r ListResult) extractPage() ([]Volume, string, error) {
+
+	var resp struct {
+		Volumes []Volume `json:"volumes" mapstructure:"volumes"`
+		Links   []Link   `json:"volumes_links" mapstructure:"volumes_links"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	next := ""
+	for _, link := range resp.Links {
+		if link.Rel == "next" {
+			next = link.Href
+		}
+	}
+
+	return resp.Volumes, next, err
+}
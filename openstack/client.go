@@ -18,9 +18,64 @@ import (
 	"github.com/rackspace/gophercloud"
 )
 
-// NewImageService creates a ServiceClient that may be used with the v2 object storage package.
-func NewImageService(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error) {
+// EndpointOpts selects endpoints of services from service catalog.
+// Region and availability (interface) apply to all services, override applies only to Glance.
+type EndpointOpts struct {
+	gophercloud.EndpointOpts
+
+	// Override is used as Glance endpoint instead of endpoint found in service catalog
+	Override string
+}
+
+// NewImageService creates a ServiceClient that may be used to access Glance API.
+func NewImageService(client *gophercloud.ProviderClient, eo EndpointOpts) (*gophercloud.ServiceClient, error) {
+	if eo.Override != "" {
+		return &gophercloud.ServiceClient{ProviderClient: client, Endpoint: gophercloud.NormalizeURL(eo.Override)}, nil
+	}
+
 	eo.ApplyDefaults("image")
+	url, err := client.EndpointLocator(eo.EndpointOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &gophercloud.ServiceClient{ProviderClient: client, Endpoint: url}, nil
+}
+
+// NewComputeService creates a ServiceClient that may be used to access Nova API.
+func NewComputeService(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error) {
+	eo.ApplyDefaults("compute")
+	url, err := client.EndpointLocator(eo)
+	if err != nil {
+		return nil, err
+	}
+	return &gophercloud.ServiceClient{ProviderClient: client, Endpoint: url}, nil
+}
+
+// blockStorageTypes lists service types under which Cinder may be registered in catalog, starting with preferred one
+var blockStorageTypes = []string{"volumev3", "volumev2", "block-storage", "volume"}
+
+// NewBlockStorageService creates a ServiceClient that may be used to access Cinder API.
+// First service type from blockStorageTypes found in catalog is used, unless type is set in endpoint options.
+func NewBlockStorageService(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error) {
+	if eo.Type != "" {
+		return newServiceClient(client, eo)
+	}
+
+	var lastErr error
+	for _, serviceType := range blockStorageTypes {
+		opts := eo
+		opts.Type = serviceType
+		sc, err := newServiceClient(client, opts)
+		if err == nil {
+			return sc, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func newServiceClient(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error) {
+	eo.ApplyDefaults(eo.Type)
 	url, err := client.EndpointLocator(eo)
 	if err != nil {
 		return nil, err
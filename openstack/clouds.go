@@ -0,0 +1,245 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// cloudsConfig represents content of clouds.yaml and secure.yaml files used by OpenStack clients
+type cloudsConfig struct {
+	Clouds map[string]cloud `yaml:"clouds"`
+}
+
+type cloud struct {
+	Auth   cloudAuth `yaml:"auth"`
+	CACert string    `yaml:"cacert"`
+	Cert   string    `yaml:"cert"`
+	Key    string    `yaml:"key"`
+	Verify *bool     `yaml:"verify"`
+}
+
+type cloudAuth struct {
+	AuthURL                     string `yaml:"auth_url"`
+	Username                    string `yaml:"username"`
+	Password                    string `yaml:"password"`
+	ProjectName                 string `yaml:"project_name"`
+	TenantName                  string `yaml:"tenant_name"`
+	ProjectID                   string `yaml:"project_id"`
+	TenantID                    string `yaml:"tenant_id"`
+	UserDomainName              string `yaml:"user_domain_name"`
+	UserDomainID                string `yaml:"user_domain_id"`
+	ProjectDomainName           string `yaml:"project_domain_name"`
+	ProjectDomainID             string `yaml:"project_domain_id"`
+	DomainName                  string `yaml:"domain_name"`
+	DomainID                    string `yaml:"domain_id"`
+	ApplicationCredentialID     string `yaml:"application_credential_id"`
+	ApplicationCredentialName   string `yaml:"application_credential_name"`
+	ApplicationCredentialSecret string `yaml:"application_credential_secret"`
+	Token                       string `yaml:"token"`
+	TrustID                     string `yaml:"trust_id"`
+}
+
+// cloudsDirs returns standard locations of clouds.yaml and secure.yaml, in order of precedence
+func cloudsDirs() []string {
+	dirs := []string{"."}
+	if home := os.Getenv("HOME"); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".config", "openstack"))
+	}
+	return append(dirs, filepath.Join("/etc", "openstack"))
+}
+
+// LoadCloud reads auth options of named cloud from clouds.yaml merged with secure.yaml.
+// File given by cloudsFile (or OS_CLIENT_CONFIG_FILE) is used instead of standard locations of clouds.yaml,
+// secure.yaml is then looked up in the same directory (or taken from OS_CLIENT_SECURE_FILE).
+func LoadCloud(name, cloudsFile string) (AuthOptions, error) {
+	if cloudsFile == "" {
+		cloudsFile = os.Getenv("OS_CLIENT_CONFIG_FILE")
+	}
+
+	secureFile := os.Getenv("OS_CLIENT_SECURE_FILE")
+	if cloudsFile != "" {
+		if secureFile == "" {
+			secureFile = findFile([]string{filepath.Dir(cloudsFile)}, "secure.yaml")
+		}
+	} else {
+		cloudsFile = findFile(cloudsDirs(), "clouds.yaml")
+		if cloudsFile == "" {
+			return AuthOptions{}, fmt.Errorf("Cloud %s requested, but clouds.yaml not found in %v", name, cloudsDirs())
+		}
+		if secureFile == "" {
+			secureFile = findFile(cloudsDirs(), "secure.yaml")
+		}
+	}
+
+	clouds, err := readCloudsFile(cloudsFile)
+	if err != nil {
+		return AuthOptions{}, err
+	}
+
+	entry, found := clouds.Clouds[name]
+	if !found {
+		return AuthOptions{}, fmt.Errorf("Cloud %s not found in %s", name, cloudsFile)
+	}
+	opts := entry.toAuthOptions()
+
+	if secureFile != "" {
+		secure, err := readCloudsFile(secureFile)
+		if err != nil {
+			return AuthOptions{}, err
+		}
+		// secrets kept in secure.yaml take precedence over values from clouds.yaml
+		if secureEntry, found := secure.Clouds[name]; found {
+			opts = secureEntry.toAuthOptions().WithDefaults(opts)
+		}
+	}
+
+	return opts, nil
+}
+
+// AuthOptionsFromEnv reads auth options from OS_* environment variables used by OpenStack clients
+func AuthOptionsFromEnv() AuthOptions {
+	return AuthOptions{
+		IdentityEndpoint:            os.Getenv("OS_AUTH_URL"),
+		Username:                    os.Getenv("OS_USERNAME"),
+		Password:                    os.Getenv("OS_PASSWORD"),
+		TenantName:                  firstNonEmpty(os.Getenv("OS_PROJECT_NAME"), os.Getenv("OS_TENANT_NAME")),
+		TenantID:                    firstNonEmpty(os.Getenv("OS_PROJECT_ID"), os.Getenv("OS_TENANT_ID")),
+		DomainName:                  os.Getenv("OS_DOMAIN_NAME"),
+		DomainID:                    os.Getenv("OS_DOMAIN_ID"),
+		UserDomainName:              os.Getenv("OS_USER_DOMAIN_NAME"),
+		UserDomainID:                os.Getenv("OS_USER_DOMAIN_ID"),
+		ProjectDomainName:           os.Getenv("OS_PROJECT_DOMAIN_NAME"),
+		ProjectDomainID:             os.Getenv("OS_PROJECT_DOMAIN_ID"),
+		ApplicationCredentialID:     os.Getenv("OS_APPLICATION_CREDENTIAL_ID"),
+		ApplicationCredentialName:   os.Getenv("OS_APPLICATION_CREDENTIAL_NAME"),
+		ApplicationCredentialSecret: os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET"),
+		TokenID:                     os.Getenv("OS_TOKEN"),
+		TrustID:                     os.Getenv("OS_TRUST_ID"),
+		Transport: TransportOptions{
+			CAFile:   os.Getenv("OS_CACERT"),
+			CertFile: os.Getenv("OS_CERT"),
+			KeyFile:  os.Getenv("OS_KEY"),
+			Insecure: os.Getenv("OS_INSECURE") == "true",
+		},
+	}
+}
+
+// WithDefaults returns copy of options where empty values are filled with values from defaults
+func (opts AuthOptions) WithDefaults(defaults AuthOptions) AuthOptions {
+	opts.IdentityEndpoint = firstNonEmpty(opts.IdentityEndpoint, defaults.IdentityEndpoint)
+	opts.Username = firstNonEmpty(opts.Username, defaults.Username)
+	opts.Password = firstNonEmpty(opts.Password, defaults.Password)
+	opts.TenantName = firstNonEmpty(opts.TenantName, defaults.TenantName)
+	opts.TenantID = firstNonEmpty(opts.TenantID, defaults.TenantID)
+	opts.DomainName = firstNonEmpty(opts.DomainName, defaults.DomainName)
+	opts.DomainID = firstNonEmpty(opts.DomainID, defaults.DomainID)
+	opts.UserDomainName = firstNonEmpty(opts.UserDomainName, defaults.UserDomainName)
+	opts.UserDomainID = firstNonEmpty(opts.UserDomainID, defaults.UserDomainID)
+	opts.ProjectDomainName = firstNonEmpty(opts.ProjectDomainName, defaults.ProjectDomainName)
+	opts.ProjectDomainID = firstNonEmpty(opts.ProjectDomainID, defaults.ProjectDomainID)
+	opts.ApplicationCredentialID = firstNonEmpty(opts.ApplicationCredentialID, defaults.ApplicationCredentialID)
+	opts.ApplicationCredentialName = firstNonEmpty(opts.ApplicationCredentialName, defaults.ApplicationCredentialName)
+	opts.ApplicationCredentialSecret = firstNonEmpty(opts.ApplicationCredentialSecret, defaults.ApplicationCredentialSecret)
+	opts.TokenID = firstNonEmpty(opts.TokenID, defaults.TokenID)
+	opts.TrustID = firstNonEmpty(opts.TrustID, defaults.TrustID)
+	opts.Transport.CAFile = firstNonEmpty(opts.Transport.CAFile, defaults.Transport.CAFile)
+	opts.Transport.CertFile = firstNonEmpty(opts.Transport.CertFile, defaults.Transport.CertFile)
+	opts.Transport.KeyFile = firstNonEmpty(opts.Transport.KeyFile, defaults.Transport.KeyFile)
+	opts.Transport.Insecure = opts.Transport.Insecure || defaults.Transport.Insecure
+	opts.Transport.ProxyURL = firstNonEmpty(opts.Transport.ProxyURL, defaults.Transport.ProxyURL)
+	if opts.Transport.Timeout == 0 {
+		opts.Transport.Timeout = defaults.Transport.Timeout
+	}
+	if opts.Transport.ConnectTimeout == 0 {
+		opts.Transport.ConnectTimeout = defaults.Transport.ConnectTimeout
+	}
+	if opts.Transport.MaxAttempts == 0 {
+		opts.Transport.MaxAttempts = defaults.Transport.MaxAttempts
+	}
+	return opts
+}
+
+func (c cloud) toAuthOptions() AuthOptions {
+	opts := c.Auth.toAuthOptions()
+	opts.Transport = TransportOptions{
+		CAFile:   c.CACert,
+		CertFile: c.Cert,
+		KeyFile:  c.Key,
+		Insecure: c.Verify != nil && !*c.Verify,
+	}
+	return opts
+}
+
+func (a cloudAuth) toAuthOptions() AuthOptions {
+	return AuthOptions{
+		IdentityEndpoint:            a.AuthURL,
+		Username:                    a.Username,
+		Password:                    a.Password,
+		TenantName:                  firstNonEmpty(a.ProjectName, a.TenantName),
+		TenantID:                    firstNonEmpty(a.ProjectID, a.TenantID),
+		DomainName:                  a.DomainName,
+		DomainID:                    a.DomainID,
+		UserDomainName:              a.UserDomainName,
+		UserDomainID:                a.UserDomainID,
+		ProjectDomainName:           a.ProjectDomainName,
+		ProjectDomainID:             a.ProjectDomainID,
+		ApplicationCredentialID:     a.ApplicationCredentialID,
+		ApplicationCredentialName:   a.ApplicationCredentialName,
+		ApplicationCredentialSecret: a.ApplicationCredentialSecret,
+		TokenID:                     a.Token,
+		TrustID:                     a.TrustID,
+	}
+}
+
+func readCloudsFile(path string) (cloudsConfig, error) {
+	var clouds cloudsConfig
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return clouds, err
+	}
+
+	if err := yaml.Unmarshal(data, &clouds); err != nil {
+		return clouds, fmt.Errorf("Cannot parse %s: %v", path, err)
+	}
+
+	return clouds, nil
+}
+
+// findFile returns path of the first existing file with given name in listed directories or empty string
+func findFile(dirs []string, name string) string {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/suite"
+)
+
+type CloudsSuite struct {
+	suite.Suite
+	Dir string
+}
+
+func (s *CloudsSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "clouds")
+	s.Require().NoError(err)
+	s.Dir = dir
+
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "clouds.yaml"), []byte(`
+clouds:
+  devstack:
+    auth:
+      auth_url: http://keystone.example.org:5000/v3
+      username: admin
+      project_name: demo
+      user_domain_name: Default
+      project_domain_name: Default
+  appcred:
+    auth_type: v3applicationcredential
+    auth:
+      auth_url: http://keystone.example.org:5000/v3
+      application_credential_id: 423f19a4ac1e4f48bbb4180756e6eb6c
+`), 0600))
+
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "secure.yaml"), []byte(`
+clouds:
+  devstack:
+    auth:
+      password: secret
+  appcred:
+    auth:
+      application_credential_secret: rEaqvJka48mpv
+`), 0600))
+}
+
+func (s *CloudsSuite) TearDownSuite() {
+	os.RemoveAll(s.Dir)
+}
+
+func (s *CloudsSuite) TestLoadCloud() {
+	Convey("Given clouds.yaml and secure.yaml", s.T(), func() {
+		cloudsFile := filepath.Join(s.Dir, "clouds.yaml")
+
+		Convey("When password cloud is loaded", func() {
+			opts, err := LoadCloud("devstack", cloudsFile)
+
+			Convey("Then auth options are merged from both files", func() {
+				So(err, ShouldBeNil)
+				So(opts.IdentityEndpoint, ShouldEqual, "http://keystone.example.org:5000/v3")
+				So(opts.Username, ShouldEqual, "admin")
+				So(opts.Password, ShouldEqual, "secret")
+				So(opts.TenantName, ShouldEqual, "demo")
+				So(opts.UserDomainName, ShouldEqual, "Default")
+				So(opts.ProjectDomainName, ShouldEqual, "Default")
+				So(opts.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("When application credential cloud is loaded", func() {
+			opts, err := LoadCloud("appcred", cloudsFile)
+
+			Convey("Then secret is taken from secure.yaml", func() {
+				So(err, ShouldBeNil)
+				So(opts.ApplicationCredentialID, ShouldEqual, "423f19a4ac1e4f48bbb4180756e6eb6c")
+				So(opts.ApplicationCredentialSecret, ShouldEqual, "rEaqvJka48mpv")
+				So(opts.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("When unknown cloud is loaded", func() {
+			_, err := LoadCloud("unknown", cloudsFile)
+
+			Convey("Then error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "unknown")
+			})
+		})
+	})
+}
+
+func (s *CloudsSuite) TestAuthOptionsFromEnv() {
+	Convey("Given OS_* environment variables", s.T(), func() {
+		os.Setenv("OS_AUTH_URL", "http://keystone.example.org:5000/v3")
+		os.Setenv("OS_USERNAME", "admin")
+		os.Setenv("OS_PROJECT_NAME", "demo")
+		defer os.Unsetenv("OS_AUTH_URL")
+		defer os.Unsetenv("OS_USERNAME")
+		defer os.Unsetenv("OS_PROJECT_NAME")
+
+		Convey("When they are used as defaults", func() {
+			opts := AuthOptions{Username: "me", Password: "secret"}.WithDefaults(AuthOptionsFromEnv())
+
+			Convey("Then only missing values are filled", func() {
+				So(opts.IdentityEndpoint, ShouldEqual, "http://keystone.example.org:5000/v3")
+				So(opts.Username, ShouldEqual, "me")
+				So(opts.Password, ShouldEqual, "secret")
+				So(opts.TenantName, ShouldEqual, "demo")
+			})
+		})
+	})
+}
+
+func TestCloudsSuite(t *testing.T) {
+	cloudsTestSuite := new(CloudsSuite)
+	suite.Run(t, cloudsTestSuite)
+}
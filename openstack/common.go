@@ -18,12 +18,16 @@ package openstack
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/rackspace/gophercloud"
 	"github.com/rackspace/gophercloud/openstack"
 	"github.com/rackspace/gophercloud/openstack/identity/v2/tenants"
 
 	"github.com/intelsdi-x/snap-plugin-collector-glance/apiversions"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/blockstorage/v2/volumes"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/compute/v2/servers"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/identity/v3/catalog"
 	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
 )
 
@@ -38,27 +42,35 @@ var apiPriority = map[string]int{
 
 // Commoner provides abstraction for shared functions mainly for mocking
 type Commoner interface {
-	GetTenants(endpoint, user, password string) ([]types.Tenant, error)
+	GetTenants(opts AuthOptions) ([]types.Tenant, error)
 	GetApiVersions(provider *gophercloud.ProviderClient) ([]types.ApiVersion, error)
+	GetServersUsage(provider *gophercloud.ProviderClient) (map[string]int, error)
+	GetVolumesUsage(provider *gophercloud.ProviderClient) (map[string]int, error)
+	GetRegions(provider *gophercloud.ProviderClient) ([]string, error)
 }
 
 // Common is a receiver for Commoner interface
-type Common struct{}
+type Common struct {
+	// Endpoint selects endpoints of services used by common functions
+	Endpoint EndpointOpts
+}
 
 // GetTenants is used to retrieve list of available tenant for authenticated user
-// List of tenants can then be used to authenticate user for each given tenant
-func (c Common) GetTenants(endpoint, user, password string) ([]types.Tenant, error) {
+// List of tenants can then be used to authenticate user for each given tenant, so tenant given in options is ignored
+func (c Common) GetTenants(opts AuthOptions) ([]types.Tenant, error) {
 	tnts := []types.Tenant{}
 
-	provider, err := Authenticate(endpoint, user, password, "", "", "")
+	opts.TenantName = ""
+	opts.TenantID = ""
+	provider, err := Authenticate(opts)
 	if err != nil {
 		return nil, err
 	}
 
 	client := openstack.NewIdentityV2(provider)
 
-	opts := tenants.ListOpts{}
-	pager := tenants.List(client, &opts)
+	listOpts := tenants.ListOpts{}
+	pager := tenants.List(client, &listOpts)
 
 	page, err := pager.AllPages()
 	if err != nil {
@@ -82,55 +94,167 @@ func (c Common) GetTenants(endpoint, user, password string) ([]types.Tenant, err
 func (c Common) GetApiVersions(provider *gophercloud.ProviderClient) ([]types.ApiVersion, error) {
 	apis := []types.ApiVersion{}
 
-	client, err := NewImageService(provider, gophercloud.EndpointOpts{
-		Availability: gophercloud.AvailabilityInternal},
-	)
-
+	client, err := NewImageService(provider, c.Endpoint)
 	if err != nil {
 		return apis, err
 	}
 
 	apiVersions, err := apiversions.Get(client).Extract()
 	if err != nil {
-		return apis, err
+		return apis, CheckTimeout("Glance API version discovery request", err)
 	}
 
 	for _, apiVersion := range apiVersions {
 		link := apiVersion.Links[0]
 		apis = append(apis, types.ApiVersion{
-			ID:   apiVersion.ID,
-			Link: link["href"],
+			ID:     apiVersion.ID,
+			Link:   link["href"],
+			Status: apiVersion.Status,
 		})
 	}
 
 	return apis, nil
 }
 
+// GetServersUsage is used to retrieve number of Nova servers booted from each image
+// Servers of all tenants are taken into account, which requires admin role
+func (c Common) GetServersUsage(provider *gophercloud.ProviderClient) (map[string]int, error) {
+	usage := map[string]int{}
+
+	client, err := NewComputeService(provider, c.Endpoint.EndpointOpts)
+	if err != nil {
+		return usage, err
+	}
+
+	srvs, err := servers.List(client).Extract()
+	if err != nil {
+		return usage, err
+	}
+
+	for _, srv := range srvs {
+		// servers booted from volume do not reference any image
+		if imageID := srv.ImageID(); imageID != "" {
+			usage[imageID] += 1
+		}
+	}
+
+	return usage, nil
+}
+
+// GetVolumesUsage is used to retrieve number of Cinder volumes created from each image
+// Volumes of all tenants are taken into account, which requires admin role
+func (c Common) GetVolumesUsage(provider *gophercloud.ProviderClient) (map[string]int, error) {
+	usage := map[string]int{}
+
+	client, err := NewBlockStorageService(provider, c.Endpoint.EndpointOpts)
+	if err != nil {
+		return usage, err
+	}
+
+	vols, err := volumes.List(client).Extract()
+	if err != nil {
+		return usage, err
+	}
+
+	for _, vol := range vols {
+		// volumes created from scratch or from snapshot do not reference any image
+		if imageID := vol.ImageID(); imageID != "" {
+			usage[imageID] += 1
+		}
+	}
+
+	return usage, nil
+}
+
+// GetRegions is used to retrieve sorted list of regions with Glance endpoint of selected interface
+// Service catalog is requested from Keystone v3, so regions cannot be discovered with Keystone v2
+func (c Common) GetRegions(provider *gophercloud.ProviderClient) ([]string, error) {
+	regions := []string{}
+
+	client := &gophercloud.ServiceClient{ProviderClient: provider, Endpoint: identityV3Endpoint(provider)}
+	svcCatalog, err := catalog.Get(client).Extract()
+	if err != nil {
+		return regions, fmt.Errorf("Cannot discover regions from Keystone v3 service catalog, list of regions has to be configured: %v", err)
+	}
+
+	eo := c.Endpoint.EndpointOpts
+	eo.ApplyDefaults("image")
+
+	found := map[string]bool{}
+	for _, entry := range svcCatalog.Entries {
+		if entry.Type != eo.Type {
+			continue
+		}
+		for _, endpoint := range entry.Endpoints {
+			if endpoint.Interface == string(eo.Availability) && !found[endpoint.Region] {
+				found[endpoint.Region] = true
+				regions = append(regions, endpoint.Region)
+			}
+		}
+	}
+	sort.Strings(regions)
+
+	return regions, nil
+}
+
 // Authenticate is used to authenticate user for given tenant. Request is send to provided Keystone endpoint
 // Returns authenticated provider client, which is used as a base for service clients.
-func Authenticate(endpoint, user, password, tenant, domain_name, domain_id string) (*gophercloud.ProviderClient, error) {
-	authOpts := gophercloud.AuthOptions{
-		IdentityEndpoint: endpoint,
-		Username:         user,
-		Password:         password,
-		TenantName:       tenant,
-		AllowReauth:      true,
+func Authenticate(opts AuthOptions) (*gophercloud.ProviderClient, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
-	if domain_name != "" && domain_id == "" {
-		authOpts.DomainName = domain_name
+
+	if opts.requiresV3() {
+		provider, err := authenticatedV3Client(opts)
+		if err != nil {
+			return nil, CheckTimeout("Keystone authentication request", err)
+		}
+		return provider, nil
 	}
-	if domain_id != "" && domain_name == "" {
-		authOpts.DomainID = domain_id
+
+	authOpts := gophercloud.AuthOptions{
+		IdentityEndpoint: opts.IdentityEndpoint,
+		Username:         opts.Username,
+		Password:         opts.Password,
+		TenantName:       opts.TenantName,
+		DomainName:       opts.DomainName,
+		DomainID:         opts.DomainID,
+		AllowReauth:      true,
 	}
 
-	provider, err := openstack.AuthenticatedClient(authOpts)
+	provider, err := newClient(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := openstack.Authenticate(provider, authOpts); err != nil {
+		return nil, CheckTimeout("Keystone authentication request", err)
+	}
+
 	return provider, nil
 }
 
+// CountImages aggregates number of images and bytes used by them per visibility type
+func CountImages(imgs []types.Image) (map[string]types.Images, error) {
+	imgTypes := map[string]types.Images{
+		"public":  types.Images{},
+		"private": types.Images{},
+		"shared":  types.Images{},
+	}
+
+	for _, img := range imgs {
+		if imgType, found := imgTypes[img.Visibility]; found {
+			imgType.Count += 1
+			imgType.Bytes += img.Size
+			imgTypes[img.Visibility] = imgType
+		} else {
+			return nil, fmt.Errorf("Uknown image visibility type found {%s}", img.Visibility)
+		}
+	}
+
+	return imgTypes, nil
+}
+
 // ChooseVersion returns chosen Cinder API version based on defined priority
 func ChooseVersion(recognized []types.ApiVersion) (string, error) {
 	if len(recognized) < 1 {
@@ -52,7 +52,7 @@ func (s *CommonSuite) TestGetTenants() {
 	Convey("Given tenants are requested", s.T(), func() {
 		c := Common{}
 		Convey("When Gettenants is called", func() {
-			tenants, err := c.GetTenants(th.Endpoint(), "me", "secret")
+			tenants, err := c.GetTenants(AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret"})
 
 			Convey("Then list of available tenats is returned", func() {
 				So(len(tenants), ShouldEqual, 2)
@@ -68,7 +68,7 @@ func (s *CommonSuite) TestGetAPI() {
 	Convey("Given api versions are requested", s.T(), func() {
 		c := Common{}
 		Convey("When GetAPIVersions is called", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret", TenantName: "tenant"})
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
@@ -120,6 +120,8 @@ func (s *CommonSuite) TestGetAPI() {
 				So(len(apis), ShouldEqual, 2)
 				So(apis[0].ID, ShouldEqual, "v1.0")
 				So(apis[1].ID, ShouldEqual, "v2.0")
+				So(apis[0].Status, ShouldEqual, "SUPPORTED")
+				So(apis[1].Status, ShouldEqual, "CURRENT")
 				So(err, ShouldBeNil)
 			})
 		})
@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servers
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Server represents Nova server
+type Server struct {
+	ID       string `json:"id" mapstructure:"id"`
+	Name     string `json:"name" mapstructure:"name"`
+	TenantID string `json:"tenant_id" mapstructure:"tenant_id"`
+	Status   string `json:"status" mapstructure:"status"`
+	// Image is an object with image ID or an empty string when server was booted from volume
+	Image interface{} `json:"image" mapstructure:"image"`
+}
+
+// ImageID returns ID of the image server was booted from or empty string for servers booted from volume
+func (s Server) ImageID() string {
+	image, ok := s.Image.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := image["id"].(string)
+	return id
+}
+
+// Link represents pagination link returned by Nova
+type Link struct {
+	Href string `json:"href" mapstructure:"href"`
+	Rel  string `json:"rel" mapstructure:"rel"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+	servers []Server
+}
+
+// Extract will get the Server objects out of the ListResult object.
+func (r ListResult) Extract() ([]Server, error) {
+	return r.servers, r.Err
+}
+
+// extractPage decodes single page of servers together with link to next page
+func (r ListResult) extractPage() ([]Server, string, error) {
+
+	var resp struct {
+		Servers []Server `json:"servers" mapstructure:"servers"`
+		Links   []Link   `json:"servers_links" mapstructure:"servers_links"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	next := ""
+	for _, link := range resp.Links {
+		if link.Rel == "next" {
+			next = link.Href
+		}
+	}
+
+	return resp.Servers, next, err
+}
@@ -0,0 +1,26 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"github.com/rackspace/gophercloud"
+)
+
+// Get retrieves service catalog of the token used by client
+func Get(client *gophercloud.ServiceClient) GetResult {
+	var res GetResult
+	_, res.Err = client.Get(getURL(client), &res.Body, nil)
+	return res
+}
@@ -0,0 +1,39 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+	tokens3 "github.com/rackspace/gophercloud/openstack/identity/v3/tokens"
+)
+
+type GetResult struct {
+	gophercloud.Result
+}
+
+// Extract returns service catalog in format used by gophercloud
+func (r GetResult) Extract() (*tokens3.ServiceCatalog, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Entries []tokens3.CatalogEntry `mapstructure:"catalog"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+	return &tokens3.ServiceCatalog{Entries: resp.Entries}, err
+}
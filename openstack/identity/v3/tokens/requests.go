@@ -0,0 +1,217 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rackspace/gophercloud"
+)
+
+// AuthOptions describes identity used to create Keystone v3 token.
+// It covers authentication methods which are not supported by gophercloud.
+type AuthOptions struct {
+	// Username and user domain are required for password authentication
+	// and to find application credential by name
+	Username   string
+	Password   string
+	DomainID   string
+	DomainName string
+
+	// TokenID is pre-issued token exchanged for new one, ex. scoped to trust
+	TokenID string
+
+	ApplicationCredentialID     string
+	ApplicationCredentialName   string
+	ApplicationCredentialSecret string
+
+	// TrustID scopes token to trust, trustee is authenticated with password or token
+	TrustID string
+
+	// Scope scopes token to project, it cannot be used with trust or application credential
+	Scope *Scope
+}
+
+// Scope identifies project which token is scoped to.
+// Project is identified by ID or by name and domain of the project.
+type Scope struct {
+	ProjectID   string
+	ProjectName string
+	DomainID    string
+	DomainName  string
+}
+
+// ToTokenCreateMap builds body of token create request
+func (opts AuthOptions) ToTokenCreateMap() (map[string]interface{}, error) {
+	identity := map[string]interface{}{}
+
+	switch {
+	case opts.ApplicationCredentialID != "" || opts.ApplicationCredentialName != "":
+		credential, err := opts.applicationCredential()
+		if err != nil {
+			return nil, err
+		}
+		identity["methods"] = []string{"application_credential"}
+		identity["application_credential"] = credential
+	case opts.TokenID != "":
+		identity["methods"] = []string{"token"}
+		identity["token"] = map[string]interface{}{"id": opts.TokenID}
+	case opts.Username != "":
+		user, err := opts.user()
+		if err != nil {
+			return nil, err
+		}
+		user["password"] = opts.Password
+		identity["methods"] = []string{"password"}
+		identity["password"] = map[string]interface{}{"user": user}
+	default:
+		return nil, fmt.Errorf("Application credential, token or username is required")
+	}
+
+	auth := map[string]interface{}{"identity": identity}
+
+	// application credential is already bound to project, so scope is sent only for trust or project
+	isApplicationCredential := opts.ApplicationCredentialID != "" || opts.ApplicationCredentialName != ""
+	switch {
+	case opts.TrustID != "":
+		if isApplicationCredential {
+			return nil, fmt.Errorf("Trust cannot be used with application credential")
+		}
+		if opts.Scope != nil {
+			return nil, fmt.Errorf("Trust cannot be used with project scope")
+		}
+		auth["scope"] = map[string]interface{}{
+			"OS-TRUST:trust": map[string]interface{}{"id": opts.TrustID},
+		}
+	case opts.Scope != nil:
+		if isApplicationCredential {
+			return nil, fmt.Errorf("Project scope cannot be used with application credential")
+		}
+		scope, err := opts.Scope.toScopeMap()
+		if err != nil {
+			return nil, err
+		}
+		auth["scope"] = scope
+	}
+
+	return map[string]interface{}{"auth": auth}, nil
+}
+
+func (scope Scope) toScopeMap() (map[string]interface{}, error) {
+	if scope.ProjectID != "" {
+		return map[string]interface{}{
+			"project": map[string]interface{}{"id": scope.ProjectID},
+		}, nil
+	}
+
+	if scope.ProjectName == "" {
+		return nil, fmt.Errorf("Project ID or name is required to scope token")
+	}
+
+	var domain map[string]interface{}
+	switch {
+	case scope.DomainID != "":
+		domain = map[string]interface{}{"id": scope.DomainID}
+	case scope.DomainName != "":
+		domain = map[string]interface{}{"name": scope.DomainName}
+	default:
+		return nil, fmt.Errorf("Project domain is required to scope token by project name")
+	}
+
+	return map[string]interface{}{
+		"project": map[string]interface{}{"name": scope.ProjectName, "domain": domain},
+	}, nil
+}
+
+func (opts AuthOptions) applicationCredential() (map[string]interface{}, error) {
+	if opts.ApplicationCredentialSecret == "" {
+		return nil, fmt.Errorf("Application credential secret is required")
+	}
+
+	credential := map[string]interface{}{
+		"secret": opts.ApplicationCredentialSecret,
+	}
+
+	if opts.ApplicationCredentialID != "" {
+		credential["id"] = opts.ApplicationCredentialID
+		return credential, nil
+	}
+
+	if opts.Username == "" {
+		return nil, fmt.Errorf("Username is required to authenticate with application credential name")
+	}
+	user, err := opts.user()
+	if err != nil {
+		return nil, err
+	}
+	credential["name"] = opts.ApplicationCredentialName
+	credential["user"] = user
+
+	return credential, nil
+}
+
+func (opts AuthOptions) user() (map[string]interface{}, error) {
+	user := map[string]interface{}{"name": opts.Username}
+	switch {
+	case opts.DomainID != "":
+		user["domain"] = map[string]interface{}{"id": opts.DomainID}
+	case opts.DomainName != "":
+		user["domain"] = map[string]interface{}{"name": opts.DomainName}
+	default:
+		return nil, fmt.Errorf("User domain is required to authenticate with username")
+	}
+	return user, nil
+}
+
+// Create requests new token from Keystone v3 API
+func Create(client *gophercloud.ServiceClient, opts AuthOptions) CreateResult {
+	var res CreateResult
+
+	body, err := opts.ToTokenCreateMap()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	resp, err := client.Post(tokenURL(client), body, &res.Body, &gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusCreated},
+	})
+	res.Err = err
+	if resp != nil {
+		res.Header = resp.Header
+	}
+
+	return res
+}
+
+// Get validates pre-issued token and retrieves its details, including service catalog
+func Get(client *gophercloud.ServiceClient, tokenID string) GetResult {
+	var res GetResult
+
+	resp, err := client.Get(tokenURL(client), &res.Body, &gophercloud.RequestOpts{
+		MoreHeaders: map[string]string{
+			"X-Auth-Token":    tokenID,
+			"X-Subject-Token": tokenID,
+		},
+		OkCodes: []int{http.StatusOK},
+	})
+	res.Err = err
+	if resp != nil {
+		res.Header = resp.Header
+	}
+
+	return res
+}
@@ -0,0 +1,298 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/rackspace/gophercloud"
+	th "github.com/rackspace/gophercloud/testhelper"
+)
+
+type TokensSuite struct {
+	suite.Suite
+	Token string
+}
+
+func (s *TokensSuite) SetupTest() {
+	th.SetupHTTP()
+	s.Token = "3e2a6d8b1f0c4c6a9a4f1b2c3d4e5f60"
+}
+
+func (s *TokensSuite) TearDownTest() {
+	th.TeardownHTTP()
+}
+
+func (s *TokensSuite) TestCreateApplicationCredentialByID() {
+	registerTokens(s, `
+		{
+			"auth": {
+				"identity": {
+					"methods": ["application_credential"],
+					"application_credential": {
+						"id": "423f19a4ac1e4f48bbb4180756e6eb6c",
+						"secret": "rEaqvJka48mpv"
+					}
+				}
+			}
+		}
+	`)
+
+	Convey("Given application credential ID and secret", s.T(), func() {
+		Convey("When token is created", func() {
+			res := Create(serviceClient(), AuthOptions{
+				ApplicationCredentialID:     "423f19a4ac1e4f48bbb4180756e6eb6c",
+				ApplicationCredentialSecret: "rEaqvJka48mpv",
+			})
+			token, err := res.ExtractToken()
+
+			Convey("Then token ID and expiration time are returned", func() {
+				So(err, ShouldBeNil)
+				So(token.ID, ShouldEqual, s.Token)
+				So(token.ExpiresAt.Year(), ShouldEqual, 2016)
+			})
+
+			Convey("and service catalog is returned", func() {
+				catalog, err := res.ExtractServiceCatalog()
+				So(err, ShouldBeNil)
+				So(len(catalog.Entries), ShouldEqual, 1)
+				So(catalog.Entries[0].Type, ShouldEqual, "image")
+				So(catalog.Entries[0].Endpoints[0].URL, ShouldEqual, "http://127.0.0.1:9292")
+			})
+		})
+	})
+}
+
+func (s *TokensSuite) TestCreateApplicationCredentialByName() {
+	registerTokens(s, `
+		{
+			"auth": {
+				"identity": {
+					"methods": ["application_credential"],
+					"application_credential": {
+						"name": "monitoring",
+						"secret": "rEaqvJka48mpv",
+						"user": {
+							"name": "me",
+							"domain": {"name": "Default"}
+						}
+					}
+				}
+			}
+		}
+	`)
+
+	Convey("Given application credential name, user and secret", s.T(), func() {
+		Convey("When token is created", func() {
+			token, err := Create(serviceClient(), AuthOptions{
+				Username:                    "me",
+				DomainName:                  "Default",
+				ApplicationCredentialName:   "monitoring",
+				ApplicationCredentialSecret: "rEaqvJka48mpv",
+			}).ExtractToken()
+
+			Convey("Then token is returned", func() {
+				So(err, ShouldBeNil)
+				So(token.ID, ShouldEqual, s.Token)
+			})
+		})
+	})
+}
+
+func (s *TokensSuite) TestCreateTrustScoped() {
+	registerTokens(s, `
+		{
+			"auth": {
+				"identity": {
+					"methods": ["password"],
+					"password": {
+						"user": {
+							"name": "me",
+							"password": "secret",
+							"domain": {"id": "default"}
+						}
+					}
+				},
+				"scope": {
+					"OS-TRUST:trust": {"id": "de0945a2f0f84c5fb2df6c0c5f1c3f4e"}
+				}
+			}
+		}
+	`)
+
+	Convey("Given trustee credentials and trust ID", s.T(), func() {
+		Convey("When token is created", func() {
+			token, err := Create(serviceClient(), AuthOptions{
+				Username: "me",
+				Password: "secret",
+				DomainID: "default",
+				TrustID:  "de0945a2f0f84c5fb2df6c0c5f1c3f4e",
+			}).ExtractToken()
+
+			Convey("Then trust scoped token is returned", func() {
+				So(err, ShouldBeNil)
+				So(token.ID, ShouldEqual, s.Token)
+			})
+		})
+	})
+}
+
+func (s *TokensSuite) TestCreateProjectScoped() {
+	registerTokens(s, `
+		{
+			"auth": {
+				"identity": {
+					"methods": ["password"],
+					"password": {
+						"user": {
+							"name": "me",
+							"password": "secret",
+							"domain": {"name": "users"}
+						}
+					}
+				},
+				"scope": {
+					"project": {
+						"name": "tenant",
+						"domain": {"id": "c1a3f2e0b8d94e7aa6f5d4c3b2a19080"}
+					}
+				}
+			}
+		}
+	`)
+
+	Convey("Given user and project in different domains", s.T(), func() {
+		Convey("When token is created", func() {
+			token, err := Create(serviceClient(), AuthOptions{
+				Username:   "me",
+				Password:   "secret",
+				DomainName: "users",
+				Scope: &Scope{
+					ProjectName: "tenant",
+					DomainID:    "c1a3f2e0b8d94e7aa6f5d4c3b2a19080",
+				},
+			}).ExtractToken()
+
+			Convey("Then project scoped token is returned", func() {
+				So(err, ShouldBeNil)
+				So(token.ID, ShouldEqual, s.Token)
+			})
+		})
+	})
+}
+
+func (s *TokensSuite) TestGet() {
+	th.Mux.HandleFunc("/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+		th.TestHeader(s.T(), r, "X-Subject-Token", s.Token)
+
+		w.Header().Add("X-Subject-Token", s.Token)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, `{"token": {"expires_at": "2016-02-21T14:28:30.000000Z", "catalog": []}}`)
+	})
+
+	Convey("Given pre-issued token", s.T(), func() {
+		Convey("When token is validated", func() {
+			token, err := Get(serviceClient(), s.Token).ExtractToken()
+
+			Convey("Then token details are returned", func() {
+				So(err, ShouldBeNil)
+				So(token.ID, ShouldEqual, s.Token)
+				So(token.ExpiresAt.Year(), ShouldEqual, 2016)
+			})
+		})
+	})
+}
+
+func (s *TokensSuite) TestCreateInvalidOptions() {
+	Convey("Given application credential name without user domain", s.T(), func() {
+		opts := AuthOptions{
+			Username:                    "me",
+			ApplicationCredentialName:   "monitoring",
+			ApplicationCredentialSecret: "rEaqvJka48mpv",
+		}
+
+		Convey("When token is created", func() {
+			_, err := Create(serviceClient(), opts).ExtractToken()
+
+			Convey("Then error is returned before request is sent", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "domain")
+			})
+		})
+	})
+}
+
+func TestTokensSuite(t *testing.T) {
+	tokensTestSuite := new(TokensSuite)
+	suite.Run(t, tokensTestSuite)
+}
+
+func serviceClient() *gophercloud.ServiceClient {
+	return &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       th.Endpoint() + "v3/",
+	}
+}
+
+func registerTokens(s *TokensSuite, expected string) {
+	th.Mux.HandleFunc("/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "POST")
+		th.TestJSONRequest(s.T(), r, expected)
+
+		w.Header().Add("X-Subject-Token", s.Token)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+
+		fmt.Fprintf(w, `
+			{
+				"token": {
+					"methods": ["application_credential"],
+					"expires_at": "2016-02-21T14:28:30.000000Z",
+					"project": {
+						"id": "97ea299c37bb4e04b3779039ea8aba44",
+						"name": "tenant",
+						"domain": {"id": "default", "name": "Default"}
+					},
+					"catalog": [
+						{
+							"id": "3ffe125aa59547029ed774c10b932349",
+							"name": "glance",
+							"type": "image",
+							"endpoints": [
+								{
+									"id": "7c8d9ec05e6b4c2fa3b1b6f1d0f7b8a1",
+									"interface": "public",
+									"region": "RegionOne",
+									"url": "http://127.0.0.1:9292"
+								}
+							]
+						}
+					]
+				}
+			}
+		`)
+	})
+}
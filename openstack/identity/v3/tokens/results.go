@@ -0,0 +1,89 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokens
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+	tokens3 "github.com/rackspace/gophercloud/openstack/identity/v3/tokens"
+)
+
+// Token represents Keystone v3 token
+type Token struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+type commonResult struct {
+	gophercloud.Result
+}
+
+// CreateResult is result of token create request
+type CreateResult struct {
+	commonResult
+}
+
+// GetResult is result of token validation request
+type GetResult struct {
+	commonResult
+}
+
+// ExtractToken returns token ID from X-Subject-Token header and its expiration time
+func (r commonResult) ExtractToken() (*Token, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Token struct {
+			ExpiresAt string `mapstructure:"expires_at"`
+		} `mapstructure:"token"`
+	}
+
+	if err := mapstructure.Decode(r.Body, &resp); err != nil {
+		return nil, err
+	}
+
+	id := r.Header.Get("X-Subject-Token")
+	if id == "" {
+		return nil, fmt.Errorf("Token ID not found in response headers")
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, resp.Token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{ID: id, ExpiresAt: expiresAt}, nil
+}
+
+// ExtractServiceCatalog returns service catalog in format used by gophercloud, so it can be used to locate endpoints
+func (r commonResult) ExtractServiceCatalog() (*tokens3.ServiceCatalog, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Token struct {
+			Entries []tokens3.CatalogEntry `mapstructure:"catalog"`
+		} `mapstructure:"token"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+	return &tokens3.ServiceCatalog{Entries: resp.Token.Entries}, err
+}
@@ -0,0 +1,131 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+
+	"github.com/intelsdi-x/snap-plugin-collector-glance/apiversions"
+)
+
+// ProbeResult holds outcome of single request sent to check health of Glance
+type ProbeResult struct {
+	// Status is HTTP status code of response, zero if no response was received
+	Status int `json:"status"`
+	// Latency is duration of the request in seconds
+	Latency float64 `json:"latency"`
+}
+
+// Probes holds outcome of requests checking health of single Glance endpoint
+type Probes struct {
+	Healthcheck ProbeResult `json:"healthcheck"`
+	Versions    ProbeResult `json:"versions"`
+	Images      ProbeResult `json:"images"`
+	// Available is set if Glance serves version document and lists images, and its healthcheck does not report failure
+	// Healthcheck responding with 404 is ignored, as healthcheck middleware may be disabled.
+	Available bool `json:"available"`
+}
+
+// ProbeGlance sends requests to /healthcheck, version document and /v2/images?limit=1 of Glance endpoint
+// and measures their latency. Failed request is reported by its status instead of error, error is returned
+// only if Glance endpoint cannot be found. URL of probed endpoint is returned together with results.
+// Requests are sent once with given transport options, so status and latency describe single attempt.
+func (c Common) ProbeGlance(provider *gophercloud.ProviderClient, transport TransportOptions) (string, Probes, error) {
+	transport.MaxAttempts = 1
+	httpClient, err := transport.HTTPClient()
+	if err != nil {
+		return "", Probes{}, err
+	}
+	defer closeIdleConnections(httpClient)
+
+	client, err := NewImageService(singleAttempt(provider, httpClient), c.Endpoint)
+	if err != nil {
+		return "", Probes{}, err
+	}
+
+	probes := Probes{
+		Healthcheck: probe(func() (int, error) {
+			return getStatus(client, client.ServiceURL("healthcheck"))
+		}),
+		Versions: probe(func() (int, error) {
+			res := apiversions.Get(client)
+			return res.StatusCode, res.Err
+		}),
+		Images: probe(func() (int, error) {
+			return getStatus(client, client.ServiceURL("v2", "images")+"?limit=1")
+		}),
+	}
+
+	healthy := isSuccess(probes.Healthcheck.Status) || probes.Healthcheck.Status == http.StatusNotFound
+	probes.Available = healthy && isSuccess(probes.Versions.Status) && isSuccess(probes.Images.Status)
+
+	return client.Endpoint, probes, nil
+}
+
+// singleAttempt creates provider which shares token and service catalog with given provider, but sends requests
+// with given HTTP client. Token renewed after 401 is stored in both providers.
+func singleAttempt(provider *gophercloud.ProviderClient, httpClient http.Client) *gophercloud.ProviderClient {
+	client := &gophercloud.ProviderClient{
+		IdentityBase:     provider.IdentityBase,
+		IdentityEndpoint: provider.IdentityEndpoint,
+		TokenID:          provider.TokenID,
+		EndpointLocator:  provider.EndpointLocator,
+		HTTPClient:       httpClient,
+		UserAgent:        provider.UserAgent,
+	}
+	if provider.ReauthFunc != nil {
+		client.ReauthFunc = func() error {
+			if err := provider.ReauthFunc(); err != nil {
+				return err
+			}
+			client.TokenID = provider.TokenID
+			return nil
+		}
+	}
+	return client
+}
+
+// probe measures latency of request sent by given function, which returns status of response
+func probe(send func() (int, error)) ProbeResult {
+	started := time.Now()
+	status, err := send()
+	result := ProbeResult{Status: status, Latency: time.Since(started).Seconds()}
+	if e, ok := err.(*gophercloud.UnexpectedResponseCodeError); ok {
+		result.Status = e.Actual
+	}
+	return result
+}
+
+// getStatus sends GET request to given URL and returns status of response, its body is discarded
+func getStatus(client *gophercloud.ServiceClient, url string) (int, error) {
+	resp, err := client.Request("GET", url, gophercloud.RequestOpts{})
+	if resp == nil {
+		return 0, err
+	}
+	if err == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return resp.StatusCode, err
+}
+
+func isSuccess(status int) bool {
+	return status >= http.StatusOK && status < http.StatusBadRequest
+}
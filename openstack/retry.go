@@ -0,0 +1,195 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts is number of attempts of idempotent request if it is not configured
+	DefaultMaxAttempts = 3
+	// maxRetryDelay limits delay between attempts computed with exponential backoff
+	maxRetryDelay = 8 * time.Second
+	// maxRetryAfter is the longest delay requested by Retry-After header, which plugin waits for
+	maxRetryAfter = 30 * time.Second
+)
+
+// retryBaseDelay is delay before the first retry, doubled for each next one
+var retryBaseDelay = 500 * time.Millisecond
+
+// retryableCodes are response codes of transient failures, after which request may succeed when sent again
+var retryableCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+var errRequestCanceled = errors.New("Request canceled while waiting for retry")
+
+// retryTransport sends idempotent requests again after connection errors and retryable response codes
+// Delay between attempts grows exponentially with random jitter, unless server requests it with Retry-After header.
+type retryTransport struct {
+	next        http.RoundTripper
+	maxAttempts int
+}
+
+// RoundTrip sends request at most maxAttempts times, response of the last attempt is returned
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		countRequest()
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		countRequest()
+		resp, err := t.next.RoundTrip(req)
+		if !isRetryable(resp, err) {
+			return resp, err
+		}
+		if isCanceled(req) {
+			return resp, err
+		}
+		if attempt >= t.maxAttempts {
+			if t.maxAttempts > 1 {
+				countRetriesExhausted()
+			}
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if after, found := retryAfter(resp); found {
+				if after > maxRetryAfter {
+					countRetriesExhausted()
+					return resp, err
+				}
+				delay = after
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		countRetry()
+		select {
+		case <-time.After(delay):
+		case <-req.Cancel:
+			return nil, errRequestCanceled
+		}
+	}
+}
+
+// CloseIdleConnections closes idle connections of underlying transport
+func (t *retryTransport) CloseIdleConnections() {
+	if next, ok := t.next.(interface {
+		CloseIdleConnections()
+	}); ok {
+		next.CloseIdleConnections()
+	}
+}
+
+// isCanceled checks if request was canceled, ex. by client timeout, so it must not be sent again
+func isCanceled(req *http.Request) bool {
+	select {
+	case <-req.Cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryable checks if request failed with error which is not permanent
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return isTransient(err)
+	}
+	return retryableCodes[resp.StatusCode]
+}
+
+// isTransient checks if transport error is temporary, timeout or connection reset. Other errors,
+// ex. refused connection, unknown host or invalid certificate, are not fixed by sending request again.
+func isTransient(err error) bool {
+	for err != nil {
+		if netErr, ok := err.(net.Error); ok && (netErr.Temporary() || netErr.Timeout()) {
+			return true
+		}
+		if errno, ok := err.(syscall.Errno); ok {
+			return errno == syscall.ECONNRESET
+		}
+		err = cause(err)
+	}
+	return false
+}
+
+// cause returns error wrapped by given one, ex. system call error of network operation,
+// or nil if error does not wrap any other
+func cause(err error) error {
+	switch err := err.(type) {
+	case *url.Error:
+		return err.Err
+	case *net.OpError:
+		return err.Err
+	case *os.SyscallError:
+		return err.Err
+	case interface {
+		Unwrap() error
+	}:
+		return err.Unwrap()
+	}
+	return nil
+}
+
+// backoff returns delay before given retry, random part of delay prevents many clients from retrying at once
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt-1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter reads delay requested by server, given in seconds or as HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(time.Now())
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,270 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetrySuite struct {
+	suite.Suite
+	Server *httptest.Server
+	// Failures is number of requests which fail before server recovers
+	Failures int
+	// Resets is number of requests which connection is reset before server recovers
+	Resets int
+	// Requests is number of requests received by server
+	Requests   int
+	RetryAfter string
+}
+
+func (s *RetrySuite) SetupSuite() {
+	retryBaseDelay = time.Millisecond
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Requests++
+		if s.Requests <= s.Resets {
+			reset(w)
+			return
+		}
+		if s.Requests <= s.Failures {
+			if s.RetryAfter != "" {
+				w.Header().Set("Retry-After", s.RetryAfter)
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func (s *RetrySuite) SetupTest() {
+	s.Requests = 0
+	s.Failures = 0
+	s.Resets = 0
+	s.RetryAfter = ""
+}
+
+// reset closes connection of given response without sending response, so client reads connection reset
+func reset(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+}
+
+func (s *RetrySuite) TearDownSuite() {
+	s.Server.Close()
+	retryBaseDelay = 500 * time.Millisecond
+}
+
+func (s *RetrySuite) TestRetry() {
+	Convey("Given server failing with transient error", s.T(), func() {
+		s.Requests = 0
+		s.Failures = 2
+		before := GetStats()
+
+		Convey("When GET request is sent", func() {
+			client, err := TransportOptions{}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then it is retried until it succeeds", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(s.Requests, ShouldEqual, 3)
+				So(GetStats().Retries-before.Retries, ShouldEqual, 2)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When attempts are exhausted", func() {
+			client, err := TransportOptions{MaxAttempts: 2}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then response of the last attempt is returned", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(s.Requests, ShouldEqual, 2)
+				So(GetStats().RetriesExhausted-before.RetriesExhausted, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When retries are disabled", func() {
+			client, err := TransportOptions{MaxAttempts: 1}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then request is sent once", func() {
+				So(err, ShouldBeNil)
+				So(s.Requests, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When POST request is sent", func() {
+			client, err := TransportOptions{}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Post(s.Server.URL, "application/json", nil)
+
+			Convey("Then it is not retried", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(s.Requests, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+	})
+}
+
+func (s *RetrySuite) TestRetryReset() {
+	Convey("Given server resetting connection", s.T(), func() {
+		s.Requests = 0
+		s.Resets = 1
+		before := GetStats()
+
+		Convey("When GET request is sent", func() {
+			client, err := TransportOptions{}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then it is retried until it succeeds", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(s.Requests, ShouldEqual, 2)
+				So(GetStats().Retries-before.Retries, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When retries are disabled", func() {
+			client, err := TransportOptions{MaxAttempts: 1}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			_, err = client.Get(s.Server.URL)
+
+			Convey("Then reset is returned as transient error", func() {
+				So(err, ShouldNotBeNil)
+				So(isTransient(err), ShouldBeTrue)
+				So(GetStats().Retries-before.Retries, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+// timeoutError is net.Error reporting timeout, ex. of connection attempt
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return false }
+
+func (s *RetrySuite) TestIsRetryable() {
+	Convey("Given transport errors", s.T(), func() {
+		reset := &net.OpError{Op: "read", Net: "tcp", Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}}
+		refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+		unknownCA := errors.New("x509: certificate signed by unknown authority")
+
+		Convey("When error is transient", func() {
+			Convey("Then request is retried", func() {
+				So(isRetryable(nil, timeoutError{}), ShouldBeTrue)
+				So(isRetryable(nil, reset), ShouldBeTrue)
+				So(isRetryable(nil, &url.Error{Op: "Get", URL: "http://glance", Err: reset}), ShouldBeTrue)
+			})
+		})
+
+		Convey("When error is permanent", func() {
+			Convey("Then request is not retried", func() {
+				So(isRetryable(nil, refused), ShouldBeFalse)
+				So(isRetryable(nil, unknownCA), ShouldBeFalse)
+				So(isRetryable(nil, errors.New("connection reset")), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func (s *RetrySuite) TestRetryAfter() {
+	Convey("Given server requesting delay with Retry-After", s.T(), func() {
+		s.Requests = 0
+		s.Failures = 1
+		client, err := TransportOptions{}.HTTPClient()
+		So(err, ShouldBeNil)
+
+		Convey("When requested delay is short", func() {
+			s.RetryAfter = "1"
+			start := time.Now()
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then request is retried after requested delay", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, time.Second)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When requested delay is too long", func() {
+			s.RetryAfter = "3600"
+
+			resp, err := client.Get(s.Server.URL)
+
+			Convey("Then request is not retried", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(s.Requests, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+	})
+}
+
+func (s *RetrySuite) TestBackoff() {
+	Convey("Given consecutive retries", s.T(), func() {
+		retryBaseDelay = 100 * time.Millisecond
+		defer func() { retryBaseDelay = time.Millisecond }()
+
+		Convey("Then delay grows exponentially with jitter up to the limit", func() {
+			So(backoff(1), ShouldBeBetweenOrEqual, 50*time.Millisecond, 100*time.Millisecond)
+			So(backoff(3), ShouldBeBetweenOrEqual, 200*time.Millisecond, 400*time.Millisecond)
+			So(backoff(20), ShouldBeBetweenOrEqual, maxRetryDelay/2, maxRetryDelay)
+		})
+	})
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, &RetrySuite{})
+}
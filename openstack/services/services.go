@@ -17,6 +17,8 @@ limitations under the License.
 package services
 
 import (
+	"fmt"
+
 	"github.com/rackspace/gophercloud"
 
 	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
@@ -28,11 +30,18 @@ import (
 // Glancer allows usage of different Glance API versions for metric collection
 type Glancer interface {
 	GetImages(provider *gophercloud.ProviderClient) (map[string]types.Images, error)
+	ListImages(provider *gophercloud.ProviderClient) ([]types.Image, error)
+	GetMetadefs(provider *gophercloud.ProviderClient) (map[string]types.Metadefs, error)
 }
 
 // Services serves as a API calls dispatcher
 type Service struct {
 	glancer Glancer
+
+	// Version is Glance API version negotiated by Dispatch, ex. v2.3
+	Version string
+	// URL is Glance endpoint which version was negotiated for
+	URL string
 }
 
 // Set allows to set proper API version implementation
@@ -45,28 +54,47 @@ func (s Service) GetImages(provider *gophercloud.ProviderClient) (map[string]typ
 	return s.glancer.GetImages(provider)
 }
 
+// ListImages dispatches call to proper API version calls to retrieve list of images
+func (s Service) ListImages(provider *gophercloud.ProviderClient) ([]types.Image, error) {
+	return s.glancer.ListImages(provider)
+}
+
+// GetMetadefs dispatches call to proper API version calls to collect metadata definitions metrics
+func (s Service) GetMetadefs(provider *gophercloud.ProviderClient) (map[string]types.Metadefs, error) {
+	return s.glancer.GetMetadefs(provider)
+}
+
 // Dispatch redirects to selected Glance API version based on priority
-func Dispatch(provider *gophercloud.ProviderClient) Service {
-	cmn := openstackintel.Common{}
+// Endpoint options are used for version discovery and by selected API version implementation
+func Dispatch(provider *gophercloud.ProviderClient, eo openstackintel.EndpointOpts) (Service, error) {
+	service := Service{}
+
+	cmn := openstackintel.Common{Endpoint: eo}
 	versions, err := cmn.GetApiVersions(provider)
 	if err != nil {
-		panic(err)
+		return service, err
 	}
 
 	chosen, err := openstackintel.ChooseVersion(versions)
 	if err != nil {
-		panic(err)
+		return service, err
 	}
+	service.Version = chosen
+
+	client, err := openstackintel.NewImageService(provider, eo)
+	if err != nil {
+		return service, err
+	}
+	service.URL = client.Endpoint
 
-	service := Service{}
 	switch chosen {
 	case "v1.0", "v1.1":
-		service.Set(glancev1.ServiceV1{})
+		service.Set(glancev1.ServiceV1{Endpoint: eo})
 	case "v2.0", "v2.1", "v2.2", "v2.3":
-		service.Set(glancev2.ServiceV2{})
+		service.Set(glancev2.ServiceV2{Endpoint: eo})
 	default:
-		panic("Could not select dispatcher")
+		return service, fmt.Errorf("Could not select dispatcher for Glance API version %s", chosen)
 	}
 
-	return service
+	return service, nil
 }
@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"sync/atomic"
+)
+
+// Stats holds counters of requests sent to OpenStack services since plugin start
+type Stats struct {
+	// Requests is number of attempts of requests, including retries
+	Requests int64 `json:"requests"`
+	// Retries is number of requests sent again after transient failure
+	Retries int64 `json:"retries"`
+	// RetriesExhausted is number of requests which failed after the last allowed attempt
+	RetriesExhausted int64 `json:"retries_exhausted"`
+}
+
+// ListingStats holds counters of Glance image listings since plugin start
+type ListingStats struct {
+	// Pages is number of pages of images received from Glance
+	Pages int64 `json:"pages"`
+	// Images is number of images received from Glance
+	Images int64 `json:"images"`
+}
+
+var (
+	stats        Stats
+	listingStats ListingStats
+)
+
+// GetStats returns current values of request counters
+func GetStats() Stats {
+	return Stats{
+		Requests:         atomic.LoadInt64(&stats.Requests),
+		Retries:          atomic.LoadInt64(&stats.Retries),
+		RetriesExhausted: atomic.LoadInt64(&stats.RetriesExhausted),
+	}
+}
+
+// GetListingStats returns current values of image listing counters
+func GetListingStats() ListingStats {
+	return ListingStats{
+		Pages:  atomic.LoadInt64(&listingStats.Pages),
+		Images: atomic.LoadInt64(&listingStats.Images),
+	}
+}
+
+// CountListing adds pages of images received from Glance to image listing counters
+func CountListing(pages int, images int) {
+	atomic.AddInt64(&listingStats.Pages, int64(pages))
+	atomic.AddInt64(&listingStats.Images, int64(images))
+}
+
+func countRequest() {
+	atomic.AddInt64(&stats.Requests, 1)
+}
+
+func countRetry() {
+	atomic.AddInt64(&stats.Retries, 1)
+}
+
+func countRetriesExhausted() {
+	atomic.AddInt64(&stats.RetriesExhausted, 1)
+}
@@ -0,0 +1,157 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// keep-alive period and TLS handshake timeout are the same as in default HTTP transport
+const (
+	keepAlive           = 30 * time.Second
+	tlsHandshakeTimeout = 10 * time.Second
+)
+
+// TransportOptions configure HTTP client used for requests to Keystone and to services found in its catalog
+type TransportOptions struct {
+	// CAFile is PEM bundle of certificate authorities trusted instead of system ones
+	CAFile string
+	// CertFile and KeyFile hold client certificate and its private key, both are required for client authentication
+	CertFile string
+	KeyFile  string
+	// Insecure disables verification of server certificates
+	Insecure bool
+
+	// Timeout limits duration of whole request including reading of response, zero means no limit
+	Timeout time.Duration
+	// ConnectTimeout limits time of establishing connection, zero means no limit
+	ConnectTimeout time.Duration
+	// ProxyURL is used instead of proxy given by HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	ProxyURL string
+
+	// MaxAttempts limits number of attempts of idempotent request failed with transient error,
+	// zero means default number of attempts and one disables retries
+	MaxAttempts int
+}
+
+// TimeoutError is returned when request did not complete within configured timeout
+type TimeoutError struct {
+	// Request describes request which timed out, ex. Glance image listing
+	Request string
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out: %v", e.Request, e.Err)
+}
+
+// Timeout marks TimeoutError as timeout, so it can be handled like other network timeouts
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary marks TimeoutError as temporary, request may succeed when it is sent again
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// CheckTimeout returns TimeoutError naming given request if err is a timeout, otherwise err is returned unchanged
+func CheckTimeout(request string, err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		if _, named := err.(*TimeoutError); !named {
+			return &TimeoutError{Request: request, Err: err}
+		}
+	}
+	return err
+}
+
+// HTTPClient creates HTTP client configured with given TLS, timeout, proxy and retry options
+func (opts TransportOptions) HTTPClient() (http.Client, error) {
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return http.Client{}, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return http.Client{}, fmt.Errorf("Invalid proxy URL %s, expected absolute URL, ex. http://proxy.example.org:3128", opts.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: keepAlive}
+	transport := &http.Transport{
+		Proxy:               proxy,
+		Dial:                dialer.Dial,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return http.Client{Transport: &retryTransport{next: transport, maxAttempts: maxAttempts}, Timeout: opts.Timeout}, nil
+}
+
+// closeIdleConnections closes connections kept alive by HTTP client created by HTTPClient, when client is not used anymore
+func closeIdleConnections(client http.Client) {
+	if t, ok := client.Transport.(interface {
+		CloseIdleConnections()
+	}); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+func (opts TransportOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CA file: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No PEM certificates found in CA file %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("Invalid TLS options: cert_file and key_file have to be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,294 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/rackspace/gophercloud"
+)
+
+type TransportSuite struct {
+	suite.Suite
+	Server *httptest.Server
+	Dir    string
+	// ClientCerts is number of client certificates presented in the last request
+	ClientCerts int
+}
+
+func (s *TransportSuite) SetupSuite() {
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ClientCerts = len(r.TLS.PeerCertificates)
+
+		switch r.URL.Path {
+		case "/v3/auth/tokens":
+			w.Header().Add("X-Subject-Token", "5f4e3d2c1b0a49f8a7b6c5d4e3f2a1b0")
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `
+				{
+					"token": {
+						"expires_at": "%s",
+						"catalog": [
+							{
+								"id": "3ffe125aa59547029ed774c10b932349",
+								"name": "glance",
+								"type": "image",
+								"endpoints": [
+									{
+										"id": "7c8d9ec05e6b4c2fa3b1b6f1d0f7b8a1",
+										"interface": "public",
+										"region": "RegionOne",
+										"url": "%s/glance/"
+									}
+								]
+							}
+						]
+					}
+				}
+			`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano), s.Server.URL)
+		case "/glance/v2/images":
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"images": []}`)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	// client certificate is requested, but not verified, so server certificate can be used by client as well
+	s.Server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.Server.StartTLS()
+
+	dir, err := ioutil.TempDir("", "transport")
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.Dir = dir
+
+	cert := s.Server.TLS.Certificates[0]
+	key, ok := cert.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		s.T().Fatal("Unexpected type of test server key")
+	}
+	s.writePEM("cert.pem", "CERTIFICATE", cert.Certificate[0])
+	s.writePEM("key.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+func (s *TransportSuite) TearDownSuite() {
+	s.Server.Close()
+	os.RemoveAll(s.Dir)
+}
+
+func (s *TransportSuite) TestHTTPClient() {
+	Convey("Given server with certificate signed by unknown authority", s.T(), func() {
+		Convey("When default options are used", func() {
+			client, err := TransportOptions{}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			_, err = client.Get(s.Server.URL + "/glance/v2/images")
+
+			Convey("Then server certificate is rejected", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When CA file is given", func() {
+			client, err := TransportOptions{CAFile: s.path("cert.pem")}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL + "/glance/v2/images")
+
+			Convey("Then server certificate is trusted", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When insecure mode is enabled", func() {
+			client, err := TransportOptions{Insecure: true}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL + "/glance/v2/images")
+
+			Convey("Then server certificate is not verified", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When client certificate is given", func() {
+			client, err := TransportOptions{CAFile: s.path("cert.pem"), CertFile: s.path("cert.pem"), KeyFile: s.path("key.pem")}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get(s.Server.URL + "/glance/v2/images")
+
+			Convey("Then it is presented to server", func() {
+				So(err, ShouldBeNil)
+				So(s.ClientCerts, ShouldEqual, 1)
+				resp.Body.Close()
+			})
+		})
+	})
+
+	Convey("Given invalid TLS options", s.T(), func() {
+		invalid := map[string]TransportOptions{
+			"missing CA file":     {CAFile: s.path("missing.pem")},
+			"CA file without PEM": {CAFile: s.path("empty.pem")},
+			"cert without key":    {CertFile: s.path("cert.pem")},
+			"key without cert":    {KeyFile: s.path("key.pem")},
+		}
+		s.writePEM("empty.pem", "", nil)
+
+		Convey("When HTTP client is created", func() {
+			for name, opts := range invalid {
+				_, err := opts.HTTPClient()
+
+				Convey(fmt.Sprintf("Then error is reported for %s", name), func() {
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
+
+func (s *TransportSuite) TestAuthenticate() {
+	Convey("Given Keystone and Glance served over TLS", s.T(), func() {
+		opts := AuthOptions{
+			IdentityEndpoint:            s.Server.URL + "/v3/",
+			ApplicationCredentialID:     "423f19a4ac1e4f48bbb4180756e6eb6c",
+			ApplicationCredentialSecret: "rEaqvJka48mpv",
+			TenantName:                  "tenant",
+		}
+
+		Convey("When CA file is not given", func() {
+			_, err := Authenticate(opts)
+
+			Convey("Then authentication fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When CA and client certificate are given", func() {
+			opts.Transport = TransportOptions{CAFile: s.path("cert.pem"), CertFile: s.path("cert.pem"), KeyFile: s.path("key.pem")}
+
+			provider, err := Authenticate(opts)
+			So(err, ShouldBeNil)
+
+			client, err := NewImageService(provider, EndpointOpts{})
+			So(err, ShouldBeNil)
+
+			s.ClientCerts = 0
+			_, err = client.Get(client.ServiceURL("v2", "images"), nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+
+			Convey("Then the same HTTP client is used for image requests", func() {
+				So(err, ShouldBeNil)
+				So(s.ClientCerts, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func (s *TransportSuite) TestTimeout() {
+	Convey("Given server which responds slowly", s.T(), func() {
+		client, err := TransportOptions{Insecure: true, Timeout: 50 * time.Millisecond}.HTTPClient()
+		So(err, ShouldBeNil)
+
+		Convey("When response is not received within timeout", func() {
+			_, err := client.Get(s.Server.URL + "/slow")
+			err = CheckTimeout("Test request", err)
+
+			Convey("Then timeout error names the request", func() {
+				So(err, ShouldNotBeNil)
+				So(err, ShouldHaveSameTypeAs, &TimeoutError{})
+				So(err.Error(), ShouldStartWith, "Test request timed out")
+			})
+		})
+
+		Convey("When other error is checked", func() {
+			err := CheckTimeout("Test request", fmt.Errorf("Connection refused"))
+
+			Convey("Then it is not changed", func() {
+				So(err.Error(), ShouldEqual, "Connection refused")
+			})
+		})
+	})
+}
+
+func (s *TransportSuite) TestProxy() {
+	Convey("Given proxy URL", s.T(), func() {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+		}))
+		defer proxy.Close()
+
+		Convey("When request is sent", func() {
+			client, err := TransportOptions{ProxyURL: proxy.URL}.HTTPClient()
+			So(err, ShouldBeNil)
+
+			resp, err := client.Get("http://glance.example.org:9292/v2/images")
+
+			Convey("Then it goes through the proxy", func() {
+				So(err, ShouldBeNil)
+				So(proxied, ShouldEqual, "http://glance.example.org:9292/v2/images")
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When proxy URL is not absolute", func() {
+			_, err := TransportOptions{ProxyURL: "proxy:3128/"}.HTTPClient()
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestTransportSuite(t *testing.T) {
+	suite.Run(t, &TransportSuite{})
+}
+
+func (s *TransportSuite) path(name string) string {
+	return filepath.Join(s.Dir, name)
+}
+
+func (s *TransportSuite) writePEM(name, blockType string, der []byte) {
+	var data []byte
+	if der != nil {
+		data = pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	}
+	if err := ioutil.WriteFile(s.path(name), data, 0600); err != nil {
+		s.T().Fatal(err)
+	}
+}
@@ -26,26 +26,35 @@ import (
 )
 
 // ServiceV2 serves as dispatcher for Glance API version 2.0
-type ServiceV1 struct{}
+type ServiceV1 struct {
+	// Endpoint selects Glance endpoint from service catalog
+	Endpoint openstackintel.EndpointOpts
+}
 
-// GetLimits collects images by sending REST call to glancehost:9292/v1/images/detail
+// GetImages collects images by sending REST call to glancehost:9292/v1/images/detail
 func (s ServiceV1) GetImages(provider *gophercloud.ProviderClient) (map[string]types.Images, error) {
-	imgTypes := map[string]types.Images{
-		"public":  types.Images{},
-		"private": types.Images{},
-		"shared":  types.Images{},
+	imgs, err := s.ListImages(provider)
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := openstackintel.NewImageService(provider, gophercloud.EndpointOpts{})
+	return openstackintel.CountImages(imgs)
+}
+
+// ListImages retrieves list of images by sending REST call to glancehost:9292/v1/images/detail
+func (s ServiceV1) ListImages(provider *gophercloud.ProviderClient) ([]types.Image, error) {
+	client, err := openstackintel.NewImageService(provider, s.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	imgs, err := images.Get(client).Extract()
 	if err != nil {
-		return nil, err
+		return nil, openstackintel.CheckTimeout("Glance v1 image listing request", err)
 	}
+	openstackintel.CountListing(1, len(imgs))
 
+	list := []types.Image{}
 	for _, img := range imgs {
 		var visibility string
 		if img.IsPublic {
@@ -54,11 +63,21 @@ func (s ServiceV1) GetImages(provider *gophercloud.ProviderClient) (map[string]t
 			visibility = "private"
 		}
 
-		imgType := imgTypes[visibility]
-		imgType.Count += 1
-		imgType.Bytes += img.Size
-		imgTypes[visibility] = imgType
+		list = append(list, types.Image{
+			ID:         img.ID,
+			Name:       img.Name,
+			Owner:      img.Owner,
+			Visibility: visibility,
+			Status:     img.Status,
+			Size:       img.Size,
+			Checksum:   img.Checksum,
+		})
 	}
 
-	return imgTypes, nil
+	return list, nil
+}
+
+// GetMetadefs returns no catalog, metadata definitions catalog is available since Glance API version 2
+func (s ServiceV1) GetMetadefs(provider *gophercloud.ProviderClient) (map[string]types.Metadefs, error) {
+	return nil, nil
 }
@@ -47,6 +47,9 @@ type GetResult struct {
 
 // Extract will get the Volume object out of the commonResult object.
 func (r GetResult) Extract() ([]Image, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
 
 	var resp struct {
 		Images []Image `json:"images" mapstructure:"images"`
@@ -19,44 +19,113 @@ package glance
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
 
 	"github.com/rackspace/gophercloud"
 
 	openstackintel "github.com/intelsdi-x/snap-plugin-collector-glance/openstack"
 	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/v2/images"
+	"github.com/intelsdi-x/snap-plugin-collector-glance/openstack/v2/metadefs"
 	"github.com/intelsdi-x/snap-plugin-collector-glance/types"
 )
 
 // ServiceV2 serves as dispatcher for Glance API version 2.0
-type ServiceV2 struct{}
+type ServiceV2 struct {
+	// Endpoint selects Glance endpoint from service catalog
+	Endpoint openstackintel.EndpointOpts
+}
 
-// GetLimits collects images by sending REST call to glancehost:9292/v2/images
+// GetImages collects images by sending REST call to glancehost:9292/v2/images
 func (s ServiceV2) GetImages(provider *gophercloud.ProviderClient) (map[string]types.Images, error) {
-	imgTypes := map[string]types.Images{
-		"public":  types.Images{},
-		"private": types.Images{},
-		"shared":  types.Images{},
+	imgs, err := s.ListImages(provider)
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := openstackintel.NewImageService(provider, gophercloud.EndpointOpts{})
+	return openstackintel.CountImages(imgs)
+}
+
+// ListImages retrieves list of images by sending REST call to glancehost:9292/v2/images
+func (s ServiceV2) ListImages(provider *gophercloud.ProviderClient) ([]types.Image, error) {
+	client, err := openstackintel.NewImageService(provider, s.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	imgs, err := images.Get(client).Extract()
+	res := images.List(client)
+	imgs, err := res.Extract()
 	if err != nil {
-		return nil, err
+		return nil, openstackintel.CheckTimeout("Glance v2 image listing request", err)
 	}
+	openstackintel.CountListing(res.Pages, len(imgs))
 
+	list := []types.Image{}
 	for _, img := range imgs {
-		if imgType, found := imgTypes[img.Visibility]; found {
-			imgType.Count += 1
-			imgType.Bytes += img.Size
-			imgTypes[img.Visibility] = imgType
-		} else {
-			return nil, fmt.Errorf("Uknown image visibility type found {%s}", img.Visibility)
+		list = append(list, types.Image{
+			ID:         img.ID,
+			Name:       img.Name,
+			Owner:      img.Owner,
+			Visibility: img.Visibility,
+			Status:     img.Status,
+			Size:       img.Size,
+			Checksum:   img.Checksum,
+			HashAlgo:   img.OsHashAlgo,
+			HashValue:  img.OsHashValue,
+		})
+	}
+
+	return list, nil
+}
+
+// GetMetadefs collects metadata definitions catalog by sending REST calls to glancehost:9292/v2/metadefs/namespaces
+// No catalog is returned if metadata definitions API is disabled or not deployed.
+func (s ServiceV2) GetMetadefs(provider *gophercloud.ProviderClient) (map[string]types.Metadefs, error) {
+	defTypes := map[string]types.Metadefs{
+		"public":  types.Metadefs{Names: []string{}},
+		"private": types.Metadefs{Names: []string{}},
+	}
+
+	client, err := openstackintel.NewImageService(provider, s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := metadefs.List(client).Extract()
+	if e, ok := err.(*gophercloud.UnexpectedResponseCodeError); ok && e.Actual == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namespace := range namespaces {
+		defType, found := defTypes[namespace.Visibility]
+		if !found {
+			return nil, fmt.Errorf("Uknown namespace visibility type found {%s}", namespace.Visibility)
 		}
+
+		// namespace listing does not contain properties and objects, so details are needed
+		details, err := metadefs.Get(client, namespace.Namespace).Extract()
+		if err != nil {
+			return nil, err
+		}
+
+		defType.Namespaces += 1
+		defType.Properties += len(details.Properties)
+		defType.Objects += len(details.Objects)
+		defType.ResourceTypeAssociations += len(details.ResourceTypeAssociations)
+		for _, obj := range details.Objects {
+			defType.Properties += len(obj.Properties)
+		}
+		defType.Names = append(defType.Names, namespace.Namespace)
+		defTypes[namespace.Visibility] = defType
+	}
+
+	for visibility, defType := range defTypes {
+		sort.Strings(defType.Names)
+		defTypes[visibility] = defType
 	}
 
-	return imgTypes, nil
+	return defTypes, nil
 }
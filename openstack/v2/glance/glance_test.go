@@ -34,6 +34,9 @@ type GlanceV2Suite struct {
 	Images             string
 	Img1Size, Img2Size int
 	Token              string
+	Namespaces         string
+	// Markers lists markers of image listing requests, empty for the first page
+	Markers []string
 }
 
 func (s *GlanceV2Suite) SetupSuite() {
@@ -41,6 +44,7 @@ func (s *GlanceV2Suite) SetupSuite() {
 	registerRoot()
 	registerAuthentication(s)
 	registerImages(s, 1000, 2000)
+	registerMetadefs(s)
 }
 
 func (suite *GlanceV2Suite) TearDownSuite() {
@@ -56,7 +60,7 @@ func (s *GlanceV2Suite) TestGetImages() {
 	Convey("Given Glance images are requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := openstackintel.Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := openstackintel.Authenticate(openstackintel.AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret", TenantName: "tenant"})
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
@@ -78,6 +82,102 @@ func (s *GlanceV2Suite) TestGetImages() {
 	})
 }
 
+func (s *GlanceV2Suite) TestListImages() {
+	Convey("Given Glance images list is requested", s.T(), func() {
+		provider, err := openstackintel.Authenticate(openstackintel.AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret", TenantName: "tenant"})
+		th.AssertNoErr(s.T(), err)
+
+		Convey("When ListImages is called", func() {
+			before := openstackintel.GetListingStats()
+			dispatch := ServiceV2{}
+			imgs, err := dispatch.ListImages(provider)
+			after := openstackintel.GetListingStats()
+
+			Convey("Then no error reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and each received page is counted", func() {
+				So(after.Pages-before.Pages, ShouldEqual, 2)
+				So(after.Images-before.Images, ShouldEqual, 2)
+			})
+
+			Convey("and images with hashes are returned", func() {
+				So(len(imgs), ShouldEqual, 2)
+				So(imgs[0].ID, ShouldEqual, "5ead7530-3293-40d2-a0ca-f441a33a99e4")
+				So(imgs[0].Visibility, ShouldEqual, "public")
+				So(imgs[0].Size, ShouldEqual, s.Img1Size)
+				So(imgs[0].Checksum, ShouldEqual, "eb9139e4942121f22bbc2afc0400b2a4")
+				So(imgs[0].HashAlgo, ShouldEqual, "sha512")
+				So(imgs[1].HashAlgo, ShouldEqual, "")
+				So(imgs[1].Checksum, ShouldEqual, "8a40c862b5735975d82605c1dd395796")
+			})
+		})
+	})
+}
+
+func (s *GlanceV2Suite) TestListImagesPages() {
+	Convey("Given Glance images listed in two pages", s.T(), func() {
+		provider, err := openstackintel.Authenticate(openstackintel.AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret", TenantName: "tenant"})
+		th.AssertNoErr(s.T(), err)
+
+		Convey("When ListImages is called", func() {
+			s.Markers = nil
+			dispatch := ServiceV2{}
+			imgs, err := dispatch.ListImages(provider)
+
+			Convey("Then next link of the first page is followed", func() {
+				So(err, ShouldBeNil)
+				So(s.Markers, ShouldResemble, []string{"", "5ead7530-3293-40d2-a0ca-f441a33a99e4"})
+			})
+
+			Convey("and images of both pages are returned", func() {
+				So(len(imgs), ShouldEqual, 2)
+				So(imgs[0].ID, ShouldEqual, "5ead7530-3293-40d2-a0ca-f441a33a99e4")
+				So(imgs[1].ID, ShouldEqual, "e0f483ec-713f-4768-ba1a-220a16b97287")
+			})
+		})
+	})
+}
+
+func (s *GlanceV2Suite) TestGetMetadefs() {
+	Convey("Given Glance metadata definitions are requested", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := openstackintel.Authenticate(openstackintel.AuthOptions{IdentityEndpoint: th.Endpoint(), Username: "me", Password: "secret", TenantName: "tenant"})
+			th.AssertNoErr(s.T(), err)
+			th.CheckEquals(s.T(), s.Token, provider.TokenID)
+
+			Convey("and GetMetadefs called", func() {
+				dispatch := ServiceV2{}
+				defs, err := dispatch.GetMetadefs(provider)
+
+				Convey("Then no error reported", func() {
+					So(err, ShouldBeNil)
+				})
+
+				Convey("and proper public metadefs values are returned", func() {
+					public := defs["public"]
+					So(public.Namespaces, ShouldEqual, 2)
+					So(public.Properties, ShouldEqual, 4)
+					So(public.Objects, ShouldEqual, 1)
+					So(public.ResourceTypeAssociations, ShouldEqual, 3)
+					So(public.Names, ShouldResemble, []string{"OS::Compute::Quota", "OS::Compute::Watchdog"})
+				})
+
+				Convey("and proper private metadefs values are returned", func() {
+					private := defs["private"]
+					So(private.Namespaces, ShouldEqual, 1)
+					So(private.Properties, ShouldEqual, 0)
+					So(private.Objects, ShouldEqual, 0)
+					So(private.ResourceTypeAssociations, ShouldEqual, 0)
+					So(private.Names, ShouldResemble, []string{"Custom::Tenant"})
+				})
+			})
+		})
+	})
+}
+
 func registerRoot() {
 	th.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `
@@ -164,12 +264,17 @@ func registerImages(s *GlanceV2Suite, size1 int, size2 int) {
 	th.Mux.HandleFunc(s.Images, func(w http.ResponseWriter, r *http.Request) {
 		th.TestMethod(s.T(), r, "GET")
 		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+		s.Markers = append(s.Markers, r.URL.Query().Get("marker"))
 
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `
+
+		// images are split into two pages to verify that next links are followed
+		if r.URL.Query().Get("marker") == "" {
+			fmt.Fprintf(w, `
 				{
 					"first": "/v2/images",
+					"next": "/v2/images?marker=5ead7530-3293-40d2-a0ca-f441a33a99e4",
 					"images": [
 						{
 							"checksum": "eb9139e4942121f22bbc2afc0400b2a4",
@@ -182,6 +287,8 @@ func registerImages(s *GlanceV2Suite, size1 int, size2 int) {
 							"min_disk": 0,
 							"min_ram": 0,
 							"name": "cirros-0.3.4-x86_64-uec",
+							"os_hash_algo": "sha512",
+							"os_hash_value": "2d35f1b0c7ac6b1ad9e1ac8d5bc8c0e16c39f5e8e9db1a5a8e9ab3cb2c3e1fb8",
 							"owner": "ded341b6891c4524b202f08f8808986f",
 							"protected": false,
 							"ramdisk_id": "95e4ad60-adaf-469d-9711-6baec2ab8a53",
@@ -193,32 +300,168 @@ func registerImages(s *GlanceV2Suite, size1 int, size2 int) {
 							"updated_at": "2016-02-22T19:06:13Z",
 							"virtual_size": null,
 							"visibility": "public"
+						}
+					],
+					"schema": "/v2/schemas/images"
+				}
+			`, s.Img1Size)
+			return
+		}
+
+		fmt.Fprintf(w, `
+			{
+				"first": "/v2/images",
+				"images": [
+					{
+						"checksum": "8a40c862b5735975d82605c1dd395796",
+						"container_format": "aki",
+						"created_at": "2016-02-22T19:06:12Z",
+						"disk_format": "aki",
+						"file": "/v2/images/e0f483ec-713f-4768-ba1a-220a16b97287/file",
+						"id": "e0f483ec-713f-4768-ba1a-220a16b97287",
+						"min_disk": 0,
+						"min_ram": 0,
+						"name": "cirros-0.3.4-x86_64-uec-kernel",
+						"owner": "ded341b6891c4524b202f08f8808986f",
+						"protected": false,
+						"schema": "/v2/schemas/image",
+						"self": "/v2/images/e0f483ec-713f-4768-ba1a-220a16b97287",
+						"size": %d,
+						"status": "active",
+						"tags": [],
+						"updated_at": "2016-02-22T19:06:12Z",
+						"virtual_size": null,
+						"visibility": "public"
+					}
+				],
+				"schema": "/v2/schemas/images"
+			}
+		`, s.Img2Size)
+	})
+
+}
+
+func registerMetadefs(s *GlanceV2Suite) {
+	s.Namespaces = "/" + s.V2 + "/metadefs/namespaces"
+
+	th.Mux.HandleFunc(s.Namespaces, func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		// namespaces are split into two pages to verify that next links are followed
+		if r.URL.Query().Get("marker") == "" {
+			fmt.Fprintf(w, `
+				{
+					"first": "/v2/metadefs/namespaces",
+					"next": "/v2/metadefs/namespaces?marker=OS::Compute::Watchdog",
+					"namespaces": [
+						{
+							"display_name": "Compute Quota",
+							"namespace": "OS::Compute::Quota",
+							"owner": "admin",
+							"protected": true,
+							"resource_type_associations": [
+								{ "name": "OS::Nova::Flavor" }
+							],
+							"visibility": "public"
 						},
 						{
-							"checksum": "8a40c862b5735975d82605c1dd395796",
-							"container_format": "aki",
-							"created_at": "2016-02-22T19:06:12Z",
-							"disk_format": "aki",
-							"file": "/v2/images/e0f483ec-713f-4768-ba1a-220a16b97287/file",
-							"id": "e0f483ec-713f-4768-ba1a-220a16b97287",
-							"min_disk": 0,
-							"min_ram": 0,
-							"name": "cirros-0.3.4-x86_64-uec-kernel",
-							"owner": "ded341b6891c4524b202f08f8808986f",
-							"protected": false,
-							"schema": "/v2/schemas/image",
-							"self": "/v2/images/e0f483ec-713f-4768-ba1a-220a16b97287",
-							"size": %d,
-							"status": "active",
-							"tags": [],
-							"updated_at": "2016-02-22T19:06:12Z",
-							"virtual_size": null,
+							"display_name": "Watchdog Behavior",
+							"namespace": "OS::Compute::Watchdog",
+							"owner": "admin",
+							"protected": true,
+							"resource_type_associations": [
+								{ "name": "OS::Glance::Image" },
+								{ "name": "OS::Nova::Flavor" }
+							],
 							"visibility": "public"
 						}
 					],
-					"schema": "/v2/schemas/images"
+					"schema": "/v2/schemas/metadefs/namespaces"
+				}
+			`)
+			return
+		}
+
+		fmt.Fprintf(w, `
+			{
+				"first": "/v2/metadefs/namespaces",
+				"namespaces": [
+					{
+						"display_name": "Custom tenant namespace",
+						"namespace": "Custom::Tenant",
+						"owner": "ded341b6891c4524b202f08f8808986f",
+						"protected": false,
+						"visibility": "private"
+					}
+				],
+				"schema": "/v2/schemas/metadefs/namespaces"
+			}
+		`)
+	})
+
+	th.Mux.HandleFunc(s.Namespaces+"/OS::Compute::Quota", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"namespace": "OS::Compute::Quota",
+				"visibility": "public",
+				"resource_type_associations": [
+					{ "name": "OS::Nova::Flavor" }
+				],
+				"objects": [
+					{
+						"name": "CPU Limits",
+						"properties": {
+							"quota:cpu_period": { "type": "integer" },
+							"quota:cpu_quota": { "type": "integer" }
+						}
+					}
+				]
+			}
+		`)
+	})
+
+	th.Mux.HandleFunc(s.Namespaces+"/OS::Compute::Watchdog", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"namespace": "OS::Compute::Watchdog",
+				"visibility": "public",
+				"resource_type_associations": [
+					{ "name": "OS::Glance::Image" },
+					{ "name": "OS::Nova::Flavor" }
+				],
+				"properties": {
+					"hw_watchdog_action": { "type": "string" },
+					"hw_watchdog_timeout": { "type": "integer" }
 				}
-			`, s.Img1Size, s.Img2Size)
+			}
+		`)
 	})
 
+	th.Mux.HandleFunc(s.Namespaces+"/Custom::Tenant", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"namespace": "Custom::Tenant",
+				"visibility": "private"
+			}
+		`)
+	})
 }
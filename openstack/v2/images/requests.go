@@ -30,3 +30,38 @@ func Get(client *gophercloud.ServiceClient) GetResult {
 	_, res.Err = client.Get(getURL(client, "images"), &res.Body, &reqOpts)
 	return res
 }
+
+// List will retrieve all images visible for the user. Glance returns images in pages,
+// so following pages are requested as long as next link is provided.
+// To extract images from the result, call the Extract method on the ListResult.
+func List(client *gophercloud.ServiceClient) ListResult {
+	var res ListResult
+	reqOpts := gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusOK},
+	}
+
+	url := getURL(client, "images")
+	for url != "" {
+		var page GetResult
+		_, page.Err = client.Get(url, &page.Body, &reqOpts)
+		if page.Err != nil {
+			res.Err = page.Err
+			return res
+		}
+
+		imgs, next, err := page.extractPage()
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.images = append(res.images, imgs...)
+		res.Pages++
+
+		url = ""
+		if next != "" {
+			url = nextURL(client, next)
+		}
+	}
+
+	return res
+}
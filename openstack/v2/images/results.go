@@ -31,6 +31,8 @@ type Image struct {
 	MinDisk         int                 `json:"min_disk" mapstructure:"min_disk"`
 	MinRam          int                 `json:"min_ram" mapstructure:"min_ram"`
 	Name            string              `json:"name" mapstructure:"name"`
+	OsHashAlgo      string              `json:"os_hash_algo" mapstructure:"os_hash_algo"`
+	OsHashValue     string              `json:"os_hash_value" mapstructure:"os_hash_value"`
 	Owner           string              `json:"owner" mapstructure:"owner"`
 	Protected       bool                `json:"protected" mapstructure:"protected"`
 	Schema          string              `json:"schema" mapstructure:"schema"`
@@ -50,14 +52,39 @@ type GetResult struct {
 
 // Extract will get the Volume object out of the commonResult object.
 func (r GetResult) Extract() ([]Image, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	imgs, _, err := r.extractPage()
+
+	return imgs, err
+}
+
+// extractPage decodes single page of images together with link to next page
+func (r GetResult) extractPage() ([]Image, string, error) {
 
 	var resp struct {
 		First  string  `mapstructure:"first"`
+		Next   string  `mapstructure:"next"`
 		Images []Image `json:"images" mapstructure:"images"`
 		Schema string  `mapstructure:"schema"`
 	}
 
 	err := mapstructure.Decode(r.Body, &resp)
 
-	return resp.Images, err
+	return resp.Images, resp.Next, err
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+	images []Image
+	// Pages is number of pages received from Glance
+	Pages int
+}
+
+// Extract will get the Image objects out of the ListResult object.
+func (r ListResult) Extract() ([]Image, error) {
+	return r.images, r.Err
 }
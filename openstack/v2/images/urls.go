@@ -14,8 +14,17 @@ limitations under the License.
 
 package images
 
-import "github.com/rackspace/gophercloud"
+import (
+	"strings"
+
+	"github.com/rackspace/gophercloud"
+)
 
 func getURL(c *gophercloud.ServiceClient, path string) string {
 	return c.ServiceURL("v2", path)
 }
+
+// nextURL resolves link to next page returned by Glance relative to service endpoint
+func nextURL(c *gophercloud.ServiceClient, next string) string {
+	return c.ServiceURL(strings.TrimPrefix(next, "/"))
+}
@@ -0,0 +1,66 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadefs
+
+import (
+	"net/http"
+
+	"github.com/rackspace/gophercloud"
+)
+
+// List will retrieve all metadata definition namespaces visible for the user. Glance returns
+// namespaces in pages, so following pages are requested as long as next link is provided.
+// To extract namespaces from the result, call the Extract method on the ListResult.
+func List(client *gophercloud.ServiceClient) ListResult {
+	var res ListResult
+	reqOpts := gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusOK},
+	}
+
+	url := listURL(client)
+	for url != "" {
+		var page ListResult
+		_, page.Err = client.Get(url, &page.Body, &reqOpts)
+		if page.Err != nil {
+			res.Err = page.Err
+			return res
+		}
+
+		namespaces, next, err := page.extractPage()
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.namespaces = append(res.namespaces, namespaces...)
+
+		url = ""
+		if next != "" {
+			url = nextURL(client, next)
+		}
+	}
+
+	return res
+}
+
+// Get will retrieve metadata definition namespace with its properties and objects.
+// To extract the namespace from the result, call the Extract method on the GetResult.
+func Get(client *gophercloud.ServiceClient, namespace string) GetResult {
+	var res GetResult
+	reqOpts := gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusOK},
+	}
+	_, res.Err = client.Get(getURL(client, namespace), &res.Body, &reqOpts)
+	return res
+}
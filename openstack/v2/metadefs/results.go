@@ -0,0 +1,93 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadefs
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// ResourceTypeAssociation represents association of namespace with resource type, ex. OS::Nova::Flavor
+type ResourceTypeAssociation struct {
+	Name       string `json:"name" mapstructure:"name"`
+	Prefix     string `json:"prefix" mapstructure:"prefix"`
+	Properties string `json:"properties_target" mapstructure:"properties_target"`
+}
+
+// Object represents metadata definition object
+type Object struct {
+	Name        string                 `json:"name" mapstructure:"name"`
+	Description string                 `json:"description" mapstructure:"description"`
+	Properties  map[string]interface{} `json:"properties" mapstructure:"properties"`
+}
+
+// Namespace represents Glance metadata definition namespace
+type Namespace struct {
+	Namespace                string                    `json:"namespace" mapstructure:"namespace"`
+	DisplayName              string                    `json:"display_name" mapstructure:"display_name"`
+	Description              string                    `json:"description" mapstructure:"description"`
+	Visibility               string                    `json:"visibility" mapstructure:"visibility"`
+	Protected                bool                      `json:"protected" mapstructure:"protected"`
+	Owner                    string                    `json:"owner" mapstructure:"owner"`
+	ResourceTypeAssociations []ResourceTypeAssociation `json:"resource_type_associations" mapstructure:"resource_type_associations"`
+	Properties               map[string]interface{}    `json:"properties" mapstructure:"properties"`
+	Objects                  []Object                  `json:"objects" mapstructure:"objects"`
+	CreatedAt                string                    `json:"created_at" mapstructure:"created_at"`
+	UpdatedAt                string                    `json:"updated_at" mapstructure:"updated_at"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+	namespaces []Namespace
+}
+
+// Extract will get the Namespace objects out of the ListResult object.
+func (r ListResult) Extract() ([]Namespace, error) {
+	return r.namespaces, r.Err
+}
+
+// extractPage decodes single page of namespaces together with link to next page
+func (r ListResult) extractPage() ([]Namespace, string, error) {
+
+	var resp struct {
+		First      string      `mapstructure:"first"`
+		Next       string      `mapstructure:"next"`
+		Namespaces []Namespace `json:"namespaces" mapstructure:"namespaces"`
+		Schema     string      `mapstructure:"schema"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Namespaces, resp.Next, err
+}
+
+// GetResult represents the result of a get operation.
+type GetResult struct {
+	gophercloud.Result
+}
+
+// Extract will get the Namespace object out of the GetResult object.
+func (r GetResult) Extract() (*Namespace, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp Namespace
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return &resp, err
+}
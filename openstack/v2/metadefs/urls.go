@@ -0,0 +1,34 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadefs
+
+import (
+	"strings"
+
+	"github.com/rackspace/gophercloud"
+)
+
+func listURL(c *gophercloud.ServiceClient) string {
+	return c.ServiceURL("v2", "metadefs", "namespaces")
+}
+
+func getURL(c *gophercloud.ServiceClient, namespace string) string {
+	return c.ServiceURL("v2", "metadefs", "namespaces", namespace)
+}
+
+// nextURL resolves link to next page returned by Glance relative to service endpoint
+func nextURL(c *gophercloud.ServiceClient, next string) string {
+	return c.ServiceURL(strings.TrimPrefix(next, "/"))
+}
@@ -18,4 +18,6 @@ package types
 type ApiVersion struct {
 	ID   string `json:"id"`
 	Link string `json:"link"`
+	// Status is status of the version reported by Glance, ex. CURRENT, SUPPORTED or DEPRECATED
+	Status string `json:"status"`
 }
@@ -19,3 +19,38 @@ type Images struct {
 	Count int `json:"count"`
 	Bytes int `json:"bytes"`
 }
+
+// Image represents single glance image independently of API version
+type Image struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Owner      string `json:"owner"`
+	Visibility string `json:"visibility"`
+	Status     string `json:"status"`
+	Size       int    `json:"size"`
+	Checksum   string `json:"checksum"`
+	HashAlgo   string `json:"os_hash_algo"`
+	HashValue  string `json:"os_hash_value"`
+}
+
+// Duplicates represent metrics of images uploaded more than once
+type Duplicates struct {
+	Groups       int `json:"groups"`
+	Bytes        int `json:"bytes"`
+	LargestGroup int `json:"largest_group"`
+}
+
+// Delta represents changes of images observed between two collections
+type Delta struct {
+	Created       int `json:"created"`
+	Deleted       int `json:"deleted"`
+	StatusChanged int `json:"status_changed"`
+	Bytes         int `json:"bytes"`
+}
+
+// VolumeImages represent metrics of images which volumes were created from
+type VolumeImages struct {
+	Count         int `json:"count"`
+	Bytes         int `json:"bytes"`
+	PendingDelete int `json:"pending_delete"`
+}
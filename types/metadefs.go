@@ -0,0 +1,24 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// Metadefs represent glance metadata definitions catalog metrics
+type Metadefs struct {
+	Namespaces               int      `json:"namespaces"`
+	Properties               int      `json:"properties"`
+	Objects                  int      `json:"objects"`
+	ResourceTypeAssociations int      `json:"resource_type_associations"`
+	Names                    []string `json:"-"`
+}